@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/liamg/scout/pkg/scan"
+	"github.com/liamg/tml"
+)
+
+var adaptiveParallelism bool
+var minParallelism = 1
+var maxParallelism = 100
+
+func adaptiveConcurrency() scan.AdaptiveConcurrency {
+	if !adaptiveParallelism {
+		return scan.AdaptiveConcurrency{}
+	}
+	return scan.AdaptiveConcurrency{
+		Min: minParallelism,
+		Max: maxParallelism,
+	}
+}
+
+// describeRoutines summarises the parallelism flags for the scan details
+func describeRoutines() string {
+	if !adaptiveParallelism {
+		return fmt.Sprintf("%d", parallelism)
+	}
+	return fmt.Sprintf("%d (adaptive, %d-%d)", parallelism, minParallelism, maxParallelism)
+}
+
+// concurrencyStatus shows the current number of routines at the end of the progress line, when it is adaptive
+func concurrencyStatus(level int) string {
+	if !adaptiveParallelism {
+		return ""
+	}
+	return tml.Sprintf(" <blue>[%d routines]</blue>", level)
+}
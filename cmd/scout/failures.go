@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/liamg/scout/pkg/scan"
+	"github.com/liamg/tml"
+)
+
+var errorBudget = 50
+
+// errorStatus shows how many requests have failed at the end of the progress line
+func errorStatus(counts map[scan.ErrorCategory]int) string {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return ""
+	}
+	return tml.Sprintf(" <red>[%d failed]</red>", total)
+}
+
+// describeErrors summarises requests which failed without a usable response, most common first
+func describeErrors(counts map[scan.ErrorCategory]int) string {
+	var categories []scan.ErrorCategory
+	total := 0
+	for category, count := range counts {
+		categories = append(categories, category)
+		total += count
+	}
+	if total == 0 {
+		return ""
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if counts[categories[i]] != counts[categories[j]] {
+			return counts[categories[i]] > counts[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+	var parts []string
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[category], category))
+	}
+	return fmt.Sprintf("%d requests failed: %s", total, strings.Join(parts, ", "))
+}
+
+// printErrorSummary describes any failed requests at the end of the scan
+func printErrorSummary(counts map[scan.ErrorCategory]int) {
+	if summary := describeErrors(counts); summary != "" {
+		printf("\n<bold><red>%s</red></bold>", summary)
+	}
+}
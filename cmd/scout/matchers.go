@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/liamg/scout/pkg/scan"
+	"github.com/spf13/cobra"
+)
+
+// responseRules holds the values of the --match-* or --filter-* flags
+type responseRules struct {
+	status  []string
+	size    []string
+	words   []string
+	lines   []string
+	time    []string
+	regex   []string
+	headers []string
+	mode    string
+}
+
+var matchRules = responseRules{mode: "and"}
+var filterRules = responseRules{mode: "or"}
+
+// addMatcherFlags adds the --match-* and --filter-* flags to a scanning command
+func addMatcherFlags(cmd *cobra.Command) {
+	matchRules.addFlags(cmd, "match", "Only show")
+	filterRules.addFlags(cmd, "filter", "Hide")
+}
+
+func (rules *responseRules) addFlags(cmd *cobra.Command, prefix string, verb string) {
+	flags := cmd.Flags()
+	flags.StringSliceVar(&rules.status, prefix+"-status", rules.status, verb+" responses with these status codes.")
+	flags.StringSliceVar(&rules.size, prefix+"-size", rules.size, verb+" responses with these sizes e.g. 100,200-300,>1000")
+	flags.StringSliceVar(&rules.words, prefix+"-words", rules.words, verb+" responses with these word counts e.g. 0-10")
+	flags.StringSliceVar(&rules.lines, prefix+"-lines", rules.lines, verb+" responses with these line counts e.g. <5")
+	flags.StringSliceVar(&rules.time, prefix+"-time", rules.time, verb+" responses which took this many milliseconds e.g. >500")
+	flags.StringArrayVar(&rules.regex, prefix+"-regex", rules.regex, verb+" responses where this regular expression is found in the headers or body (can be specified multiple times).")
+	flags.StringArrayVar(&rules.headers, prefix+"-header", rules.headers, verb+" responses with a header matching 'Name: regex' (can be specified multiple times).")
+	flags.StringVar(&rules.mode, prefix+"-mode", rules.mode, "Whether all ('and') or any ('or') of the --"+prefix+"-* flags must match.")
+}
+
+// build converts the flag values into matchers, one for each flag that was set
+func (rules *responseRules) build(prefix string) ([]scan.Matcher, scan.Mode, error) {
+
+	mode, err := scan.ParseMode(rules.mode)
+	if err != nil {
+		return nil, mode, fmt.Errorf("--%s-mode: %s", prefix, err)
+	}
+
+	var matchers []scan.Matcher
+
+	if len(rules.status) > 0 {
+		var codes []int
+		for _, code := range rules.status {
+			i, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return nil, mode, fmt.Errorf("--%s-status: invalid status code '%s'", prefix, code)
+			}
+			codes = append(codes, i)
+		}
+		matchers = append(matchers, scan.StatusMatcher(codes...))
+	}
+
+	for _, ranged := range []struct {
+		name    string
+		values  []string
+		matcher func(...scan.Range) scan.Matcher
+	}{
+		{"size", rules.size, scan.SizeMatcher},
+		{"words", rules.words, scan.WordsMatcher},
+		{"lines", rules.lines, scan.LinesMatcher},
+		{"time", rules.time, scan.TimeMatcher},
+	} {
+		if len(ranged.values) == 0 {
+			continue
+		}
+		ranges, err := scan.ParseRanges(ranged.values)
+		if err != nil {
+			return nil, mode, fmt.Errorf("--%s-%s: %s", prefix, ranged.name, err)
+		}
+		matchers = append(matchers, ranged.matcher(ranges...))
+	}
+
+	for _, expression := range rules.regex {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, mode, fmt.Errorf("--%s-regex: %s", prefix, err)
+		}
+		matchers = append(matchers, scan.RegexMatcher(pattern))
+	}
+
+	for _, header := range rules.headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, mode, fmt.Errorf("--%s-header: expected 'Name: regex', got '%s'", prefix, header)
+		}
+		pattern, err := regexp.Compile(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, mode, fmt.Errorf("--%s-header: %s", prefix, err)
+		}
+		matchers = append(matchers, scan.HeaderMatcher(strings.TrimSpace(parts[0]), pattern))
+	}
+
+	return matchers, mode, nil
+}
+
+// buildMatchers returns the matchers and filters requested with the --match-* and --filter-* flags
+func buildMatchers() (matchers []scan.Matcher, matchMode scan.Mode, filters []scan.Matcher, filterMode scan.Mode, err error) {
+	matchers, matchMode, err = matchRules.build("match")
+	if err != nil {
+		return
+	}
+	filters, filterMode, err = filterRules.build("filter")
+	return
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/liamg/scout/pkg/output"
+	"github.com/liamg/scout/pkg/scan"
+	"github.com/liamg/tml"
+)
+
+var outputPath string
+var outputFormat = output.FormatText
+
+// structuredStdout returns true when structured results are being written to stdout, in which case everything meant
+// for humans is written to stderr instead, so stdout can be piped into other tools
+func structuredStdout() bool {
+	return outputPath == "" && !strings.EqualFold(outputFormat, output.FormatText)
+}
+
+// humanOutput returns where the banner, scan details, results and summary should be written
+func humanOutput() io.Writer {
+	if structuredStdout() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printf formats tml and writes it to the human readable output
+func printf(format string, a ...interface{}) {
+	_, _ = fmt.Fprint(humanOutput(), tml.Sprintf(format, a...))
+}
+
+// progress writes to the progress line, which always goes to stderr
+func progress(line string) {
+	_, _ = fmt.Fprint(os.Stderr, line)
+}
+
+func clearLine() {
+	_, _ = fmt.Fprint(os.Stderr, "\033[2K\r")
+}
+
+// formatResult formats a result line for humans. Values from the response are appended without tml processing, so
+// markup in them cannot affect the formatting.
+func formatResult(statusCode int, size int, target string, metadata scan.ResponseMetadata) string {
+	line := tml.Sprintf("<blue>[</blue><yellow>%d</yellow><blue>]</blue> <blue>[</blue><yellow>%d</yellow><blue>]</blue> ", statusCode, size) + target
+	if metadata.Location != "" {
+		line += tml.Sprintf(" <blue>-></blue> ") + metadata.Location
+	}
+	if metadata.Title != "" {
+		line += tml.Sprintf(" <blue>(</blue>") + metadata.Title + tml.Sprintf("<blue>)</blue>")
+	}
+	return line + "\n"
+}
+
+// fileReporter closes the output file along with the reporter
+type fileReporter struct {
+	output.Reporter
+	file *os.File
+}
+
+func (r *fileReporter) Close() error {
+	if err := r.Reporter.Close(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// openReporter creates a reporter for --output and --format, or returns nil if results are only being printed for humans
+func openReporter() (output.Reporter, error) {
+	if outputPath == "" {
+		if structuredStdout() {
+			return output.New(outputFormat, os.Stdout)
+		}
+		return nil, nil
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	reporter, err := output.New(outputFormat, file)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(outputPath)
+		return nil, err
+	}
+	return &fileReporter{Reporter: reporter, file: file}, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [state-file]",
+	Short: "Resume a scan from a state file.",
+	Long:  "Scout will continue a scan started with --state-file from where it stopped.",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if len(args) == 0 {
+			printf("<bold><red>Error:</red></bold> You must specify a state file.\n")
+			os.Exit(1)
+		}
+
+		saved, err := loadState(args[0])
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> Failed to load state: %s\n", err)
+			os.Exit(1)
+		}
+
+		if saved.Complete {
+			printf("<bold><green>This scan has already completed.</green></bold>\n")
+			return
+		}
+
+		target, flags, err := rootCmd.Find(saved.Args)
+		if err != nil || target == cmd || target.Run == nil {
+			printf("<bold><red>Error:</red></bold> The state file does not describe a scan which can be resumed.\n")
+			os.Exit(1)
+		}
+
+		if err := target.ParseFlags(flags); err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		resumedScan = saved
+		stateFile = args[0]
+
+		target.Run(target, target.Flags().Args())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/liamg/scout/pkg/scan"
+)
+
+var retries = scan.DefaultRetryPolicy.Attempts - 1
+var retryDelay = scan.DefaultRetryPolicy.BaseDelay
+var retryMaxDelay = scan.DefaultRetryPolicy.MaxDelay
+var retryJitter = scan.DefaultRetryPolicy.Jitter
+var retryOn = []string{"timeout", "reset", "429"}
+
+// buildRetryPolicy converts the retry flags into the policy for failed requests
+func buildRetryPolicy() (scan.RetryPolicy, error) {
+	policy := scan.RetryPolicy{
+		Attempts:  retries + 1,
+		BaseDelay: retryDelay,
+		MaxDelay:  retryMaxDelay,
+		Jitter:    retryJitter,
+	}
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	for _, input := range retryOn {
+		condition, err := scan.ParseRetryCondition(input)
+		if err != nil {
+			return policy, err
+		}
+		policy.Retry = append(policy.Retry, condition)
+	}
+	return policy, nil
+}
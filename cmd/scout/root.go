@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
 	"strconv"
 
 	"github.com/liamg/scout/internal/app/scout/version"
@@ -11,9 +15,9 @@ import (
 
 var parallelism = 10
 var noColours = false
-var wordlistPath string
 var debug bool
 var skipSSLVerification bool
+var proxyURL string
 var positiveStatusCodes = []int{
 	http.StatusOK,
 	http.StatusBadRequest,
@@ -31,7 +35,7 @@ var rootCmd = &cobra.Command{
 	Short: "Scout is a portable URL fuzzer and spider",
 	Long:  `A fast and portable url fuzzer and spider - see https://github.com/liamg/scout for more information`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		fmt.Printf(`
+		_, _ = fmt.Fprintf(humanOutput(), `
                           __ 
    ______________  __  __/ /_   
   / ___/ ___/ __ \/ / / / __/    %s
@@ -48,8 +52,46 @@ func init() {
 	}
 
 	rootCmd.PersistentFlags().IntVarP(&parallelism, "parallelism", "r", parallelism, "Parallel routines to use for sending requests.")
+	rootCmd.PersistentFlags().BoolVar(&adaptiveParallelism, "adaptive", adaptiveParallelism, "Adjust the number of parallel routines as the scan runs, backing off when requests slow down or fail.")
+	rootCmd.PersistentFlags().IntVar(&minParallelism, "min-parallelism", minParallelism, "Fewest parallel routines to use with --adaptive.")
+	rootCmd.PersistentFlags().IntVar(&maxParallelism, "max-parallelism", maxParallelism, "Most parallel routines to use with --adaptive.")
+	rootCmd.PersistentFlags().IntVar(&errorBudget, "error-budget", errorBudget, "Abort the scan once this many requests in a row fail without a response (0 to never abort).")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", retries, "Times to retry a failed request.")
+	rootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", retryDelay, "Wait before the first retry, doubling for each after it.")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxDelay, "retry-max-delay", retryMaxDelay, "Longest wait before a retry.")
+	rootCmd.PersistentFlags().DurationVar(&retryJitter, "retry-jitter", retryJitter, "Add a random delay of up to this long before each retry.")
+	rootCmd.PersistentFlags().StringSliceVar(&retryOn, "retry-on", retryOn, "Failures to retry: timeout, reset, 5xx and/or 429.")
+	rootCmd.PersistentFlags().StringVar(&proxyURL, "proxy", proxyURL, "Send requests through this HTTP proxy e.g. http://127.0.0.1:8080")
 	rootCmd.PersistentFlags().BoolVarP(&noColours, "no-colours", "n", noColours, "Disable coloured output.")
-	rootCmd.PersistentFlags().StringVarP(&wordlistPath, "wordlist", "w", wordlistPath, "Path to wordlist file. If this is not specified an internal wordlist will be used.")
+	rootCmd.PersistentFlags().StringArrayVarP(&wordlistPaths, "wordlist", "w", wordlistPaths, "Path to wordlist file, optionally bound to a keyword with path:KEYWORD (can be specified multiple times). If this is not specified an internal wordlist will be used.")
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", debug, "Enable debug logging.")
 	rootCmd.PersistentFlags().BoolVarP(&skipSSLVerification, "skip-ssl-verify", "k", skipSSLVerification, "Skip SSL certificate verification.")
+	rootCmd.PersistentFlags().Float64Var(&requestRate, "rate", requestRate, "Maximum requests per second across all hosts (0 for no limit).")
+	rootCmd.PersistentFlags().Float64Var(&requestRatePerHost, "rate-per-host", requestRatePerHost, "Maximum requests per second to any one host (0 for no limit).")
+	rootCmd.PersistentFlags().DurationVar(&requestJitter, "jitter", requestJitter, "Add a random delay of up to this long before each request e.g. 500ms")
+	rootCmd.PersistentFlags().StringVarP(&outputPath, "output", "o", outputPath, "Write results to this file.")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", outputFormat, "Format to write results in: text, json, ndjson or csv. Structured formats are written to stdout unless --output is set.")
+}
+
+// interruptibleContext returns a context which is cancelled when the user hits Ctrl-C, so that scans can stop
+// gracefully and report what they found. A second Ctrl-C will exit immediately.
+func interruptibleContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// parseProxy parses the proxy flag, returning nil if it is not set
+func parseProxy() (*url.URL, error) {
+	if proxyURL == "" {
+		return nil, nil
+	}
+	proxy, err := url.Parse(proxyURL)
+	if err != nil || proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url: %s", proxyURL)
+	}
+	return proxy, nil
 }
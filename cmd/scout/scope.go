@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/liamg/scout/pkg/scan"
+)
+
+var scopeIncludes []string
+var scopeExcludes []string
+var maxLinkDepth int
+var includeSubdomains bool
+var maxPerPath int
+
+// buildScope converts the scope flags into the scope applied to spidered, seeded and redirected links
+func buildScope() (scan.Scope, error) {
+	scope := scan.Scope{
+		MaxDepth:   maxLinkDepth,
+		Subdomains: includeSubdomains,
+		MaxPerPath: maxPerPath,
+	}
+	for _, include := range scopeIncludes {
+		pattern, err := regexp.Compile(include)
+		if err != nil {
+			return scope, fmt.Errorf("invalid scope inclusion '%s': %s", include, err)
+		}
+		scope.Include = append(scope.Include, pattern)
+	}
+	for _, exclude := range scopeExcludes {
+		pattern, err := regexp.Compile(exclude)
+		if err != nil {
+			return scope, fmt.Errorf("invalid scope exclusion '%s': %s", exclude, err)
+		}
+		scope.Exclude = append(scope.Exclude, pattern)
+	}
+	return scope, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/liamg/scout/pkg/scan"
+	"github.com/sirupsen/logrus"
+)
+
+const stateSaveInterval = time.Second * 10
+
+var stateFile string
+
+// resumedScan is set by the resume command before it runs the original command
+var resumedScan *savedScan
+
+// savedScan is what gets written to the --state-file, so that `scout resume` can continue the scan later
+type savedScan struct {
+	Args     []string         `json:"args"` // command line the scan was started with
+	Complete bool             `json:"complete"`
+	URL      *scan.URLState   `json:"url,omitempty"`
+	VHOST    *scan.VHOSTState `json:"vhost,omitempty"`
+}
+
+func loadState(path string) (*savedScan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var saved savedScan
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// save writes the state to a temporary file first, so a crash mid-write never leaves a corrupt state file behind
+func (saved *savedScan) save(path string) error {
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// commandArgs returns the command line which started the scan, including when it has been resumed
+func commandArgs() []string {
+	if resumedScan != nil {
+		return resumedScan.Args
+	}
+	return os.Args[1:]
+}
+
+type stateSaver struct {
+	path     string
+	snapshot func() *savedScan
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// startSavingState saves a snapshot of the scan to the --state-file every few seconds until finish is called.
+// If no state file was requested it returns nil, which is safe to call finish on.
+func startSavingState(snapshot func() *savedScan) *stateSaver {
+	if stateFile == "" {
+		return nil
+	}
+	saver := &stateSaver{
+		path:     stateFile,
+		snapshot: snapshot,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go func() {
+		defer close(saver.stopped)
+		ticker := time.NewTicker(stateSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-saver.stop:
+				return
+			case <-ticker.C:
+				if err := saver.snapshot().save(saver.path); err != nil {
+					logrus.Errorf("Failed to save state: %s", err)
+				}
+			}
+		}
+	}()
+	return saver
+}
+
+// finish stops the periodic saving and saves the final state of the scan
+func (saver *stateSaver) finish(complete bool) error {
+	if saver == nil {
+		return nil
+	}
+	close(saver.stop)
+	<-saver.stopped
+	saved := saver.snapshot()
+	saved.Complete = complete
+	return saved.save(saver.path)
+}
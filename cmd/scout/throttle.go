@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamg/scout/pkg/scan"
+	"github.com/liamg/tml"
+)
+
+var requestRate float64
+var requestRatePerHost float64
+var requestJitter time.Duration
+
+func rateLimit() scan.RateLimit {
+	return scan.RateLimit{
+		RequestsPerSecond: requestRate,
+		PerHost:           requestRatePerHost,
+		Jitter:            requestJitter,
+	}
+}
+
+// describeRateLimit summarises the rate limit flags for the scan details
+func describeRateLimit() string {
+	var limits []string
+	if requestRate > 0 {
+		limits = append(limits, fmt.Sprintf("%g/s", requestRate))
+	}
+	if requestRatePerHost > 0 {
+		limits = append(limits, fmt.Sprintf("%g/s per host", requestRatePerHost))
+	}
+	if requestJitter > 0 {
+		limits = append(limits, fmt.Sprintf("%s jitter", requestJitter))
+	}
+	if len(limits) == 0 {
+		return "-"
+	}
+	return strings.Join(limits, ", ")
+}
+
+// throttleStatus keeps track of hosts asking for requests to slow down, so it can be shown in the progress line
+type throttleStatus struct {
+	mutex sync.Mutex
+	count int
+	until time.Time
+}
+
+// watch records throttle events, writing each to the progress line as it happens
+func (t *throttleStatus) watch(events <-chan scan.ThrottleEvent, output chan<- string) {
+	defer func() {
+		_ = recover()
+	}()
+	for event := range events {
+		t.mutex.Lock()
+		t.count++
+		t.until = time.Now().Add(event.Wait)
+		t.mutex.Unlock()
+		output <- tml.Sprintf("<yellow>Throttled with a %d response, waiting %s...</yellow> ", event.StatusCode, event.Wait) + event.Host
+	}
+}
+
+// String describes any throttling so far, for the end of the progress line
+func (t *throttleStatus) String() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.count == 0 {
+		return ""
+	}
+	if remaining := time.Until(t.until); remaining > 0 {
+		return tml.Sprintf(" <yellow>[throttled %d times, resuming in %s]</yellow>", t.count, remaining.Round(time.Second))
+	}
+	return tml.Sprintf(" <yellow>[throttled %d times]</yellow>", t.count)
+}
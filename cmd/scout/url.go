@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/liamg/scout/pkg/output"
 	"github.com/liamg/scout/pkg/scan"
 	"github.com/liamg/scout/pkg/wordlist"
 	"github.com/liamg/tml"
@@ -23,7 +27,16 @@ var headers []string
 var extensions = []string{"php", "htm", "html", "txt"}
 var includeNoExtension bool
 var enableSpidering bool
+var disableSeeding bool
 var ignoredLengths []int
+var recursionDepth int
+var recursionExclusions []string
+var autoCalibrate bool
+var method = "GET"
+var requestBody string
+var requestFile string
+var requestScheme = "https"
+var requestHost string
 
 var urlCmd = &cobra.Command{
 	Use:   "url [url]",
@@ -41,19 +54,43 @@ var urlCmd = &cobra.Command{
 			tml.DisableFormatting()
 		}
 
-		if len(args) == 0 {
-			tml.Println("<bold><red>Error:</red></bold> You must specify a target URL.")
-			os.Exit(1)
-		}
-
-		parsedURL, err := url.ParseRequestURI(args[0])
-		if err != nil {
-			tml.Printf("<bold><red>Error:</red></bold> Invalid URL: %s\n", err)
-			os.Exit(1)
+		var request *scan.RawRequest
+		if requestFile != "" {
+			var err error
+			request, err = loadRawRequest(requestFile)
+			if err != nil {
+				printf("<bold><red>Error:</red></bold> Invalid request file: %s\n", err)
+				os.Exit(1)
+			}
+			// flags given alongside the request file take precedence over it
+			if cmd.Flags().Changed("method") {
+				request.Method = strings.ToUpper(method)
+			}
+			if cmd.Flags().Changed("data") {
+				request.Body = requestBody
+			}
+			request.Headers = append(request.Headers, headers...)
+		} else {
+			if len(args) == 0 {
+				printf("<bold><red>Error:</red></bold> You must specify a target URL or a request file.\n")
+				os.Exit(1)
+			}
+			parsedURL, err := url.ParseRequestURI(args[0])
+			if err != nil {
+				printf("<bold><red>Error:</red></bold> Invalid URL: %s\n", err)
+				os.Exit(1)
+			}
+			request = &scan.RawRequest{
+				Method:  strings.ToUpper(method),
+				URL:     *parsedURL,
+				Headers: headers,
+				Body:    requestBody,
+			}
 		}
 
 		resultChan := make(chan scan.URLResult)
 		busyChan := make(chan string, 0x400)
+		throttleChan := make(chan scan.ThrottleEvent, 0x10)
 
 		var intStatusCodes []int
 		var filteredStatusCodes []string
@@ -73,53 +110,157 @@ var urlCmd = &cobra.Command{
 
 			i, err := strconv.Atoi(code)
 			if err != nil {
-				tml.Printf("<bold><red>Error:</red></bold> Invalid status code entered: %s.\n", code)
+				printf("<bold><red>Error:</red></bold> Invalid status code entered: %s.\n", code)
 				os.Exit(1)
 			}
 			filteredStatusCodes = append(filteredStatusCodes, code)
 			intStatusCodes = append(intStatusCodes, i)
 		}
 
+		var recursionExclusionPatterns []*regexp.Regexp
+		for _, exclusion := range recursionExclusions {
+			pattern, err := regexp.Compile(exclusion)
+			if err != nil {
+				printf("<bold><red>Error:</red></bold> Invalid recursion exclusion '%s': %s\n", exclusion, err)
+				os.Exit(1)
+			}
+			recursionExclusionPatterns = append(recursionExclusionPatterns, pattern)
+		}
+
+		matchers, matchMode, filters, filterMode, err := buildMatchers()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		retryPolicy, err := buildRetryPolicy()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		proxy, err := parseProxy()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		scope, err := buildScope()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		visited, closeVisited, err := openVisitedSet()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		journal, closeJournal, err := openVisitedJournal()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
 		options := []scan.URLOption{
 			scan.WithPositiveStatusCodes(intStatusCodes),
 			scan.WithNegativeLengths(ignoredLengths),
-			scan.WithTargetURL(*parsedURL),
+			scan.WithRawRequest(request),
 			scan.WithResultChan(resultChan),
 			scan.WithBusyChan(busyChan),
+			scan.WithThrottleChan(throttleChan),
+			scan.WithRateLimit(rateLimit()),
 			scan.WithParallelism(parallelism),
+			scan.WithAdaptiveConcurrency(adaptiveConcurrency()),
+			scan.WithErrorBudget(errorBudget),
+			scan.WithRetryPolicy(retryPolicy),
 			scan.WithExtensions(extensions),
 			scan.WithIncludeNoExtension(includeNoExtension),
 			scan.WithFilename(filename),
 			scan.WithSkipSSLVerification(skipSSLVerification),
-			scan.WithExtraHeaders(headers),
+			scan.WithProxy(proxy),
 			scan.WithSpidering(enableSpidering),
+			scan.WithSeeding(!disableSeeding),
+			scan.WithScope(scope),
+			scan.WithVisitedSet(visited),
+			scan.WithVisitedJournal(journal),
+			scan.WithRecursion(recursionDepth),
+			scan.WithRecursionExclusions(recursionExclusionPatterns),
+			scan.WithAutoCalibration(autoCalibrate),
+			scan.WithMatchers(matchMode, matchers...),
+			scan.WithFilters(filterMode, filters...),
+		}
+
+		if resumedScan != nil && resumedScan.URL != nil {
+			options = append(options, scan.WithState(resumedScan.URL))
+		}
+
+		words, err := openWordlist()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+		if combination, ok := words.(wordlist.Combination); ok {
+			template := strings.Join(append([]string{request.URL.String(), request.Method, request.Body}, request.Headers...), "\n")
+			for _, keyword := range combination.Keywords() {
+				if !strings.Contains(template, keyword) {
+					printf("<bold><red>Error:</red></bold> The keyword %s does not appear in the url, method, headers or body.\n", keyword)
+					os.Exit(1)
+				}
+			}
+		}
+		if words != nil {
+			options = append(options, scan.WithWordlist(words))
 		}
 
-		if wordlistPath != "" {
-			words, err := wordlist.FromFile(wordlistPath)
+		scanner := scan.NewURLScanner(options...)
+
+		var baselines []string
+		if autoCalibrate {
+			calibrated, err := scanner.Calibrate()
 			if err != nil {
-				tml.Printf("<bold><red>Error:</red></bold> %s\n", err)
+				printf("<bold><red>Error:</red></bold> Calibration failed: %s\n", err)
 				os.Exit(1)
 			}
-			options = append(options, scan.WithWordlist(words))
+			for _, baseline := range calibrated {
+				baselines = append(baselines, baseline.String())
+			}
+		}
+		if len(baselines) == 0 {
+			baselines = append(baselines, "-")
 		}
 
-		tml.Printf(
+		printf(
 			`<blue>[</blue><yellow>+</yellow><blue>] Target URL</blue><yellow>      %s
-<blue>[</blue><yellow>+</yellow><blue>] Routines</blue><yellow>        %d 
+<blue>[</blue><yellow>+</yellow><blue>] Method</blue><yellow>          %s
+<blue>[</blue><yellow>+</yellow><blue>] Routines</blue><yellow>        %s
+<blue>[</blue><yellow>+</yellow><blue>] Rate Limit</blue><yellow>      %s
 <blue>[</blue><yellow>+</yellow><blue>] Extensions</blue><yellow>      %s 
 <blue>[</blue><yellow>+</yellow><blue>] Positive Codes</blue><yellow>  %s
 <blue>[</blue><yellow>+</yellow><blue>] Spider</blue><yellow>          %t
+<blue>[</blue><yellow>+</yellow><blue>] Seed</blue><yellow>            %t
+<blue>[</blue><yellow>+</yellow><blue>] Recursion Depth</blue><yellow> %d
+<blue>[</blue><yellow>+</yellow><blue>] Baseline</blue><yellow>        %s
 
 `,
-			parsedURL.String(),
-			parallelism,
+			request.URL.String(),
+			request.Method,
+			describeRoutines(),
+			describeRateLimit(),
 			strings.Join(extensions, ","),
 			strings.Join(filteredStatusCodes, ","),
 			enableSpidering,
+			!disableSeeding,
+			recursionDepth,
+			strings.Join(baselines, "\n                    "),
 		)
 
-		scanner := scan.NewURLScanner(options...)
+		reporter, err := openReporter()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
 
 		waitChan := make(chan struct{})
 
@@ -128,17 +269,27 @@ var urlCmd = &cobra.Command{
 
 		go func() {
 			for result := range resultChan {
-				importantOutputChan <- tml.Sprintf("<blue>[</blue><yellow>%d</yellow><blue>]</blue> <blue>[</blue><yellow>%d</yellow><blue>]</blue> %s\n", result.StatusCode, result.Size, result.URL.String())
+				if reporter != nil {
+					if err := reporter.ReportURL(result); err != nil {
+						logrus.Errorf("Failed to write result: %s", err)
+					}
+				}
+				if !structuredStdout() {
+					importantOutputChan <- formatResult(result.StatusCode, result.Size, output.DescribeURL(result), result.ResponseMetadata)
+				}
 			}
 			close(waitChan)
 		}()
 
+		throttled := &throttleStatus{}
+		go throttled.watch(throttleChan, genericOutputChan)
+
 		go func() {
 			defer func() {
 				_ = recover()
 			}()
 			for uri := range busyChan {
-				genericOutputChan <- tml.Sprintf("Checking %s...", uri)
+				genericOutputChan <- tml.Sprintf("Checking %s...", uri) + concurrencyStatus(scanner.Concurrency()) + throttled.String() + errorStatus(scanner.Errors())
 			}
 		}()
 
@@ -151,7 +302,7 @@ var urlCmd = &cobra.Command{
 				select {
 				case output := <-importantOutputChan:
 					clearLine()
-					fmt.Print(output)
+					_, _ = fmt.Fprint(humanOutput(), output)
 				FLUSH:
 					for {
 						select {
@@ -167,16 +318,32 @@ var urlCmd = &cobra.Command{
 					return
 				case output := <-genericOutputChan:
 					clearLine()
-					fmt.Print(output)
+					progress(output)
 				}
 			}
 
 		}()
 
-		results, err := scanner.Scan()
-		if err != nil {
+		ctx, stop := interruptibleContext()
+		defer stop()
+
+		saver := startSavingState(func() *savedScan {
+			return &savedScan{Args: commandArgs(), URL: scanner.State()}
+		})
+
+		results, err := scanner.ScanContext(ctx)
+		if saveErr := saver.finish(err == nil); saveErr != nil {
 			clearLine()
-			tml.Printf("<bold><red>Error:</red></bold> %s\n", err)
+			printf("<bold><red>Error:</red></bold> Failed to save state: %s\n", saveErr)
+		}
+		closeJournal(err == nil)
+		closeVisited()
+		interrupted := errors.Is(err, context.Canceled)
+		if err != nil && !interrupted {
+			clearLine()
+			printf("<bold><red>Error:</red></bold> %s", err)
+			printErrorSummary(scanner.Errors())
+			printf("\n")
 			os.Exit(1)
 		}
 		logrus.Debug("Waiting for output to flush...")
@@ -184,14 +351,33 @@ var urlCmd = &cobra.Command{
 		close(genericOutputChan)
 		<-outChan
 
+		if reporter != nil {
+			if err := reporter.Close(); err != nil {
+				clearLine()
+				printf("<bold><red>Error:</red></bold> Failed to write results: %s\n", err)
+			}
+		}
+
 		clearLine()
-		tml.Printf("\n<bold><green>Scan complete. %d results found.</green></bold>\n\n", len(results))
+		if interrupted {
+			printf("\n<bold><yellow>Scan interrupted.</yellow></bold>")
+			if stateFile != "" {
+				printf("\n<bold><yellow>Run 'scout resume %s' to continue.</yellow></bold>", stateFile)
+			}
+		}
+		printErrorSummary(scanner.Errors())
+		printf("\n<bold><green>Scan complete. %d results found.</green></bold>\n\n", len(results))
 
 	},
 }
 
-func clearLine() {
-	fmt.Printf("\033[2K\r")
+func loadRawRequest(path string) (*scan.RawRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return scan.ParseRawRequest(file, requestScheme, requestHost)
 }
 
 func init() {
@@ -201,8 +387,30 @@ func init() {
 	urlCmd.Flags().StringSliceVarP(&extensions, "extensions", "x", extensions, "File extensions to detect.")
 	urlCmd.Flags().BoolVarP(&includeNoExtension, "include-no-extension", "X", includeNoExtension, "Include URLs with no extension.")
 	urlCmd.Flags().StringSliceVarP(&headers, "header", "H", headers, "Extra header to send with requests (can be specified multiple times).")
+	urlCmd.Flags().StringVarP(&method, "method", "m", method, "HTTP method to use.")
+	urlCmd.Flags().StringVar(&requestBody, "data", requestBody, "Body to send with requests.")
+	urlCmd.Flags().StringVarP(&requestFile, "request", "q", requestFile, "File containing a raw HTTP request to use as the template for every request e.g. one saved from Burp.")
+	urlCmd.Flags().StringVar(&requestScheme, "request-scheme", requestScheme, "Scheme to use with --request, if the request line does not include one.")
+	urlCmd.Flags().StringVar(&requestHost, "request-host", requestHost, "Host to send requests to with --request, instead of its Host header.")
+	urlCmd.Flags().StringVar(&wordlistMode, "wordlist-mode", wordlistMode, "How to combine several wordlists: 'clusterbomb' tries every combination, 'pitchfork' pairs the nth word of each.")
 	urlCmd.Flags().BoolVarP(&enableSpidering, "spider", "s", enableSpidering, "Spider links within page content")
+	urlCmd.Flags().BoolVar(&disableSeeding, "no-seed", disableSeeding, "Don't check the paths listed in robots.txt, sitemap.xml and security.txt before brute-forcing.")
+	urlCmd.Flags().StringSliceVar(&scopeIncludes, "scope-include", scopeIncludes, "Regular expressions matching the paths of spidered, seeded and redirected links which should be checked - all others are ignored.")
+	urlCmd.Flags().StringSliceVar(&scopeExcludes, "scope-exclude", scopeExcludes, "Regular expressions matching the paths of spidered, seeded and redirected links which should never be checked e.g. ^/logout")
+	urlCmd.Flags().IntVar(&maxLinkDepth, "max-link-depth", maxLinkDepth, "How many links to follow away from brute-forced URLs when spidering (0 for no limit).")
+	urlCmd.Flags().BoolVar(&includeSubdomains, "subdomains", includeSubdomains, "Follow links to subdomains of the target host.")
+	urlCmd.Flags().IntVar(&maxPerPath, "max-per-path", maxPerPath, "How many links to check for any one path, whatever their query strings (0 for no limit).")
+	urlCmd.Flags().StringVar(&visitedMode, "visited", visitedMode, "How to remember which requests have been made: 'map' is exact, 'bloom' uses fixed memory but may skip a few requests, 'disk' is exact and keeps them on disk.")
+	urlCmd.Flags().IntVar(&visitedCapacity, "visited-capacity", visitedCapacity, "How many requests to size the bloom filter for, with --visited bloom.")
+	urlCmd.Flags().Float64Var(&visitedFalsePositiveRate, "visited-fp-rate", visitedFalsePositiveRate, "Proportion of requests which may be wrongly skipped once the bloom filter is full, with --visited bloom.")
+	urlCmd.Flags().StringVar(&visitedDir, "visited-dir", visitedDir, "Directory to keep the visited set in, with --visited disk (defaults to the system temporary directory).")
 	urlCmd.Flags().IntSliceVarP(&ignoredLengths, "hide-lengths", "l", ignoredLengths, "Hide results with these content lengths")
+	urlCmd.Flags().IntVarP(&recursionDepth, "recursion-depth", "R", recursionDepth, "Brute-force discovered directories, up to this many levels below the target URL. Each word is also tried as a directory, with a trailing slash, unless -X is given.")
+	urlCmd.Flags().BoolVarP(&autoCalibrate, "auto-calibrate", "a", autoCalibrate, "Request random non-existent paths before scanning each directory and hide results which look the same.")
+	urlCmd.Flags().StringVar(&stateFile, "state-file", stateFile, "Periodically save the progress of the scan to this file, so it can be continued with 'scout resume'.")
+	urlCmd.Flags().StringSliceVar(&recursionExclusions, "recursion-exclude", recursionExclusions, "Regular expressions matching directory paths which should not be recursed into.")
+
+	addMatcherFlags(urlCmd)
 
 	rootCmd.AddCommand(urlCmd)
 }
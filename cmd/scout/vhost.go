@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -16,10 +18,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var ip string
+var ips []string
+var allIPs bool
 var port int
+var portList string
+var schemeName string
 var useSSL bool
 var contentHashing bool
+var vhostStatusCodes []string // any code which differs from the baseline's is positive unless these are set
+var baselines = scan.DefaultVHOSTOptions.Baselines
+var similarity = scan.DefaultSimilarity
+var verifySSL bool
 
 var vhostCmd = &cobra.Command{
 	Use:   "vhost [base_domain]",
@@ -38,7 +47,7 @@ var vhostCmd = &cobra.Command{
 		}
 
 		if len(args) == 0 {
-			tml.Println("<bold><red>Error:</red></bold> You must specify a base domain.")
+			printf("<bold><red>Error:</red></bold> You must specify a base domain.\n")
 			os.Exit(1)
 		}
 
@@ -58,64 +67,200 @@ var vhostCmd = &cobra.Command{
 
 		resultChan := make(chan scan.VHOSTResult)
 		busyChan := make(chan string, 0x400)
+		throttleChan := make(chan scan.ThrottleEvent, 0x10)
 
 		var intStatusCodes []int
+		var filteredStatusCodes []string
+		var hiddenStatusCodes []int
 
-		for _, code := range statusCodes {
+		for _, code := range hideStatusCodes {
 			i, err := strconv.Atoi(code)
 			if err != nil {
-				tml.Printf("<bold><red>Error:</red></bold> Invalid status code entered: %s.\n", code)
+				printf("<bold><red>Error:</red></bold> Invalid status code entered: %s.\n", code)
 				os.Exit(1)
 			}
+			hiddenStatusCodes = append(hiddenStatusCodes, i)
+		}
+
+		for _, code := range vhostStatusCodes {
+
+			var skip bool
+			for _, ignoreCode := range hideStatusCodes {
+				if ignoreCode == code {
+					skip = true
+					break
+				}
+			}
+			if skip {
+				continue
+			}
+
+			i, err := strconv.Atoi(code)
+			if err != nil {
+				printf("<bold><red>Error:</red></bold> Invalid status code entered: %s.\n", code)
+				os.Exit(1)
+			}
+			filteredStatusCodes = append(filteredStatusCodes, code)
 			intStatusCodes = append(intStatusCodes, i)
 		}
 
-		ipStr := ip
+		if len(vhostStatusCodes) > 0 && len(intStatusCodes) == 0 {
+			printf("<bold><red>Error:</red></bold> Every status code given with --status-codes is hidden.\n")
+			os.Exit(1)
+		}
+
+		positiveCodes := strings.Join(filteredStatusCodes, ",")
+		if len(vhostStatusCodes) == 0 {
+			positiveCodes = "any which differ from the baseline"
+		}
+
+		ipStr := strings.Join(ips, ",")
 		if ipStr == "" {
 			ipStr = "-"
+			if allIPs {
+				ipStr = "all"
+			}
 		}
-
-		portStr := strconv.Itoa(port)
-		if port == 0 {
+		var ports []int
+		if port != 0 {
+			ports = append(ports, port)
+		}
+		if portList != "" {
+			listed, err := scan.ParsePorts(portList)
+			if err != nil {
+				printf("<bold><red>Error:</red></bold> %s\n", err)
+				os.Exit(1)
+			}
+			ports = append(ports, listed...)
+		}
+		portStrs := make([]string, 0, len(ports))
+		for _, p := range ports {
+			portStrs = append(portStrs, strconv.Itoa(p))
+		}
+		portStr := strings.Join(portStrs, ",")
+		if portStr == "" {
 			portStr = "-"
 		}
 
-		options := &scan.VHOSTOptions{
-			BaseDomain:     baseDomain,
-			Parallelism:    parallelism,
-			ResultChan:     resultChan,
-			BusyChan:       busyChan,
-			UseSSL:         useSSL,
-			IP:             ip,
-			Port:           port,
-			ContentHashing: contentHashing,
+		scheme := scan.SchemeHTTP
+		if useSSL {
+			scheme = scan.SchemeHTTPS
 		}
-		if wordlistPath != "" {
-			var err error
-			options.Wordlist, err = wordlist.FromFile(wordlistPath)
+		if schemeName != "" {
+			parsed, err := scan.ParseScheme(schemeName)
 			if err != nil {
-				tml.Printf("<bold><red>Error:</red></bold> %s\n", err)
+				printf("<bold><red>Error:</red></bold> %s\n", err)
+				os.Exit(1)
+			}
+			scheme = parsed
+		}
+
+		// results are only told apart by origin if several may be scanned
+		multipleOrigins := allIPs || len(ips) > 1 || strings.Contains(ipStr, "/") || len(ports) > 1 ||
+			scheme == scan.SchemeBoth || (scheme == scan.SchemeAuto && len(ports) == 0)
+
+		matchers, matchMode, filters, filterMode, err := buildMatchers()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		retryPolicy, err := buildRetryPolicy()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		proxy, err := parseProxy()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+
+		for _, header := range headers {
+			if name := strings.SplitN(header, ":", 2)[0]; strings.EqualFold(strings.TrimSpace(name), "host") {
+				printf("<bold><red>Error:</red></bold> The Host header is what a vhost scan varies, so cannot be set with -H.\n")
 				os.Exit(1)
 			}
 		}
-		options.Inherit()
 
-		tml.Printf(
+		if skipSSLVerification && verifySSL {
+			printf("<bold><red>Error:</red></bold> -k and --verify-ssl cannot be used together.\n")
+			os.Exit(1)
+		}
+
+		options := []scan.VHOSTOption{
+			scan.WithVHOSTBaseDomain(baseDomain),
+			scan.WithVHOSTParallelism(parallelism),
+			scan.WithVHOSTResultChan(resultChan),
+			scan.WithVHOSTBusyChan(busyChan),
+			scan.WithVHOSTThrottleChan(throttleChan),
+			scan.WithVHOSTRateLimit(rateLimit()),
+			scan.WithVHOSTAdaptiveConcurrency(adaptiveConcurrency()),
+			scan.WithVHOSTErrorBudget(errorBudget),
+			scan.WithVHOSTRetryPolicy(retryPolicy),
+			scan.WithVHOSTSSL(useSSL),
+			scan.WithVHOSTSSLVerification(verifySSL),
+			scan.WithVHOSTIPs(ips...),
+			scan.WithVHOSTAllIPs(allIPs),
+			scan.WithVHOSTPorts(ports...),
+			scan.WithVHOSTScheme(scheme),
+			scan.WithVHOSTProxy(proxy),
+			scan.WithVHOSTMethod(strings.ToUpper(method)),
+			scan.WithVHOSTHeaders(headers),
+			scan.WithVHOSTPositiveStatusCodes(intStatusCodes),
+			scan.WithVHOSTNegativeStatusCodes(hiddenStatusCodes),
+			scan.WithVHOSTNegativeLengths(ignoredLengths),
+			scan.WithVHOSTContentHashing(contentHashing),
+			scan.WithVHOSTBaselines(baselines),
+			scan.WithVHOSTSimilarity(similarity),
+			scan.WithVHOSTMatchers(matchMode, matchers...),
+			scan.WithVHOSTFilters(filterMode, filters...),
+		}
+		words, err := openWordlist()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
+		if _, ok := words.(wordlist.Combination); ok {
+			printf("<bold><red>Error:</red></bold> VHOST scans support a single wordlist only.\n")
+			os.Exit(1)
+		}
+		if words != nil {
+			options = append(options, scan.WithVHOSTWordlist(words))
+		}
+		if resumedScan != nil && resumedScan.VHOST != nil {
+			options = append(options, scan.WithVHOSTState(resumedScan.VHOST))
+		}
+
+		printf(
 			`<blue>[</blue><yellow>+</yellow><blue>] Base Domain</blue><yellow>     %s
-<blue>[</blue><yellow>+</yellow><blue>] Routines</blue><yellow>        %d 
+<blue>[</blue><yellow>+</yellow><blue>] Routines</blue><yellow>        %s
+<blue>[</blue><yellow>+</yellow><blue>] Rate Limit</blue><yellow>      %s
 <blue>[</blue><yellow>+</yellow><blue>] IP</blue><yellow>              %s 
 <blue>[</blue><yellow>+</yellow><blue>] Port</blue><yellow>            %s 
-<blue>[</blue><yellow>+</yellow><blue>] Using SSL</blue><yellow>       %t
+<blue>[</blue><yellow>+</yellow><blue>] Scheme</blue><yellow>          %s
+<blue>[</blue><yellow>+</yellow><blue>] Method</blue><yellow>          %s
+<blue>[</blue><yellow>+</yellow><blue>] Positive Codes</blue><yellow>  %s
 
 `,
-			options.BaseDomain,
-			options.Parallelism,
+			baseDomain,
+			describeRoutines(),
+			describeRateLimit(),
 			ipStr,
 			portStr,
-			options.UseSSL,
+			scheme,
+			strings.ToUpper(method),
+			positiveCodes,
 		)
 
-		scanner := scan.NewVHOSTScanner(options)
+		scanner := scan.NewVHOSTScanner(options...)
+
+		reporter, err := openReporter()
+		if err != nil {
+			printf("<bold><red>Error:</red></bold> %s\n", err)
+			os.Exit(1)
+		}
 
 		waitChan := make(chan struct{})
 
@@ -124,17 +269,31 @@ var vhostCmd = &cobra.Command{
 
 		go func() {
 			for result := range resultChan {
-				importantOutputChan <- tml.Sprintf("%s\n", result.VHOST)
+				if reporter != nil {
+					if err := reporter.ReportVHOST(result); err != nil {
+						logrus.Errorf("Failed to write result: %s", err)
+					}
+				}
+				if !structuredStdout() {
+					target := result.VHOST
+					if multipleOrigins {
+						target += " on " + result.Origin
+					}
+					importantOutputChan <- formatResult(result.StatusCode, result.Size, target, result.ResponseMetadata)
+				}
 			}
 			close(waitChan)
 		}()
 
+		throttled := &throttleStatus{}
+		go throttled.watch(throttleChan, genericOutputChan)
+
 		go func() {
 			defer func() {
 				_ = recover()
 			}()
 			for uri := range busyChan {
-				genericOutputChan <- tml.Sprintf("Checking %s...", uri)
+				genericOutputChan <- tml.Sprintf("Checking %s...", uri) + concurrencyStatus(scanner.Concurrency()) + throttled.String() + errorStatus(scanner.Errors())
 			}
 		}()
 
@@ -147,7 +306,7 @@ var vhostCmd = &cobra.Command{
 				select {
 				case output := <-importantOutputChan:
 					clearLine()
-					fmt.Printf(output)
+					_, _ = fmt.Fprint(humanOutput(), output)
 				FLUSH:
 					for {
 						select {
@@ -163,16 +322,30 @@ var vhostCmd = &cobra.Command{
 					return
 				case output := <-genericOutputChan:
 					clearLine()
-					fmt.Printf(output)
+					progress(output)
 				}
 			}
 
 		}()
 
-		results, err := scanner.Scan()
-		if err != nil {
+		ctx, stop := interruptibleContext()
+		defer stop()
+
+		saver := startSavingState(func() *savedScan {
+			return &savedScan{Args: commandArgs(), VHOST: scanner.State()}
+		})
+
+		results, err := scanner.ScanContext(ctx)
+		if saveErr := saver.finish(err == nil); saveErr != nil {
 			clearLine()
-			tml.Printf("<bold><red>Error:</red></bold> %s\n", err)
+			printf("<bold><red>Error:</red></bold> Failed to save state: %s\n", saveErr)
+		}
+		interrupted := errors.Is(err, context.Canceled)
+		if err != nil && !interrupted {
+			clearLine()
+			printf("<bold><red>Error:</red></bold> %s", err)
+			printErrorSummary(scanner.Errors())
+			printf("\n")
 			os.Exit(1)
 		}
 		logrus.Debug("Waiting for output to flush...")
@@ -180,8 +353,22 @@ var vhostCmd = &cobra.Command{
 		close(genericOutputChan)
 		<-outChan
 
+		if reporter != nil {
+			if err := reporter.Close(); err != nil {
+				clearLine()
+				printf("<bold><red>Error:</red></bold> Failed to write results: %s\n", err)
+			}
+		}
+
 		clearLine()
-		tml.Printf("\n<bold><green>Scan complete. %d results found.</green></bold>\n\n", len(results))
+		if interrupted {
+			printf("\n<bold><yellow>Scan interrupted.</yellow></bold>")
+			if stateFile != "" {
+				printf("\n<bold><yellow>Run 'scout resume %s' to continue.</yellow></bold>", stateFile)
+			}
+		}
+		printErrorSummary(scanner.Errors())
+		printf("\n<bold><green>Scan complete. %d results found.</green></bold>\n\n", len(results))
 
 	},
 }
@@ -189,9 +376,24 @@ var vhostCmd = &cobra.Command{
 func init() {
 
 	vhostCmd.Flags().BoolVar(&useSSL, "ssl", useSSL, "Use HTTPS when connecting to the server.")
-	vhostCmd.Flags().StringVar(&ip, "ip", ip, "IP address to connect to - defaults to the DNS A record for the base domain.")
+	vhostCmd.Flags().StringSliceVar(&ips, "ip", ips, "IP addresses or CIDR ranges to check every vhost against - defaults to the first DNS A or AAAA record for the base domain.")
+	vhostCmd.Flags().BoolVar(&allIPs, "all-ips", allIPs, "Check every vhost against all of the base domain's DNS A and AAAA records.")
 	vhostCmd.Flags().IntVar(&port, "port", port, "Port to connect to - defaults to 80 or 443 if --ssl is set.")
-	vhostCmd.Flags().BoolVar(&contentHashing, "hash-contents", contentHashing, "Hash each response body to detect differences for catch-all scenarios.")
+	vhostCmd.Flags().StringVar(&portList, "ports", portList, "Ports and ranges to check every vhost on e.g. 80,443,8000-8100")
+	vhostCmd.Flags().StringVar(&schemeName, "scheme", schemeName, "Scheme to use: http, https, both, or auto to detect HTTPS on each port with a TLS handshake. Defaults to https if --ssl is set, otherwise http.")
+	vhostCmd.Flags().BoolVar(&contentHashing, "hash-contents", contentHashing, "Compare each response body with those of non-existent vhosts to detect differences for catch-all scenarios.")
+	vhostCmd.Flags().IntVar(&baselines, "baselines", baselines, "Random non-existent vhosts to request to learn what the server responds with for them.")
+	vhostCmd.Flags().IntVar(&similarity.Distance, "similarity-distance", similarity.Distance, "With --hash-contents, hide bodies whose fingerprints differ from a non-existent vhost's by at most this many bits (out of 64).")
+	vhostCmd.Flags().Float64Var(&similarity.Tolerance, "similarity-tolerance", similarity.Tolerance, "With --hash-contents, hide bodies whose size and word count differ from a non-existent vhost's by at most this fraction.")
+	vhostCmd.Flags().BoolVar(&verifySSL, "verify-ssl", verifySSL, "Verify SSL certificates, which are not checked by default as guessed vhosts rarely match them - so -k has no effect on vhost scans.")
+	vhostCmd.Flags().StringSliceVarP(&vhostStatusCodes, "status-codes", "c", vhostStatusCodes, "HTTP status codes which indicate a positive find - by default, any which differs from that of a vhost which does not exist.")
+	vhostCmd.Flags().StringSliceVarP(&hideStatusCodes, "hide-status-codes", "z", hideStatusCodes, "HTTP status codes which should be hidden.")
+	vhostCmd.Flags().StringSliceVarP(&headers, "header", "H", headers, "Extra header to send with requests (can be specified multiple times).")
+	vhostCmd.Flags().StringVarP(&method, "method", "m", method, "HTTP method to use.")
+	vhostCmd.Flags().IntSliceVarP(&ignoredLengths, "hide-lengths", "l", ignoredLengths, "Hide results with these content lengths")
+	vhostCmd.Flags().StringVar(&stateFile, "state-file", stateFile, "Periodically save the progress of the scan to this file, so it can be continued with 'scout resume'.")
+
+	addMatcherFlags(vhostCmd)
 
 	rootCmd.AddCommand(vhostCmd)
 }
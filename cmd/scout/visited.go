@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/liamg/scout/pkg/scan"
+)
+
+var visitedMode = "map"
+var visitedCapacity = 10000000
+var visitedFalsePositiveRate = 0.0001
+var visitedDir string
+
+// openVisitedSet creates the visited set chosen with --visited, along with a function which releases it
+func openVisitedSet() (scan.VisitedSet, func(), error) {
+	switch visitedMode {
+	case "map":
+		return scan.NewMapVisitedSet(), func() {}, nil
+	case "bloom":
+		return scan.NewBloomVisitedSet(visitedCapacity, visitedFalsePositiveRate), func() {}, nil
+	case "disk":
+		set, err := scan.NewDiskVisitedSet(visitedDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create visited set: %s", err)
+		}
+		return set, func() { _ = set.Close() }, nil
+	}
+	return nil, nil, fmt.Errorf("invalid --visited '%s': must be 'map', 'bloom' or 'disk'", visitedMode)
+}
+
+// openVisitedJournal opens the journal of requests made which is kept beside the --state-file, if there is one, along
+// with a function which closes it, removing it once the scan is complete
+func openVisitedJournal() (*scan.VisitedJournal, func(complete bool), error) {
+	if stateFile == "" {
+		return nil, func(bool) {}, nil
+	}
+	path := stateFile + ".visited"
+	journal, err := scan.OpenVisitedJournal(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open visited journal: %s", err)
+	}
+	return journal, func(complete bool) {
+		_ = journal.Close()
+		if complete {
+			_ = os.Remove(path)
+		}
+	}, nil
+}
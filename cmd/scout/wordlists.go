@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/liamg/scout/pkg/scan"
+	"github.com/liamg/scout/pkg/wordlist"
+)
+
+var wordlistPaths []string
+var wordlistMode = "clusterbomb"
+
+var keywordPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// splitKeyword separates a "path:KEYWORD" wordlist argument into its path and keyword. Paths without a keyword are
+// bound to FUZZ.
+func splitKeyword(arg string) (string, string) {
+	if index := strings.LastIndex(arg, ":"); index > 0 && keywordPattern.MatchString(arg[index+1:]) {
+		return arg[:index], arg[index+1:]
+	}
+	return arg, scan.FuzzKeyword
+}
+
+// openWordlist opens the wordlists given with -w. If several are given, or any is bound to a keyword, they are
+// combined according to --wordlist-mode. If none are given it returns nil, so the internal wordlist is used.
+func openWordlist() (wordlist.Wordlist, error) {
+
+	if len(wordlistPaths) == 0 {
+		return nil, nil
+	}
+
+	if len(wordlistPaths) == 1 && !strings.Contains(wordlistPaths[0], ":") {
+		return wordlist.FromFile(wordlistPaths[0])
+	}
+
+	var lists []wordlist.KeywordList
+	keywords := make(map[string]struct{})
+	for _, arg := range wordlistPaths {
+		path, keyword := splitKeyword(arg)
+		if _, ok := keywords[keyword]; ok {
+			return nil, fmt.Errorf("more than one wordlist is bound to the keyword %s", keyword)
+		}
+		keywords[keyword] = struct{}{}
+		words, err := wordlist.FromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, wordlist.KeywordList{Keyword: keyword, Wordlist: words})
+	}
+
+	if len(lists) == 1 && lists[0].Keyword == scan.FuzzKeyword {
+		return lists[0].Wordlist, nil
+	}
+
+	switch wordlistMode {
+	case "clusterbomb":
+		return wordlist.ClusterBomb(lists...), nil
+	case "pitchfork":
+		return wordlist.Pitchfork(lists...), nil
+	default:
+		return nil, fmt.Errorf("invalid wordlist mode '%s': must be 'clusterbomb' or 'pitchfork'", wordlistMode)
+	}
+}
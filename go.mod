@@ -3,11 +3,11 @@ module github.com/liamg/scout
 go 1.19
 
 require (
-	github.com/avast/retry-go v2.4.3+incompatible
 	github.com/liamg/tml v0.2.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/cobra v0.0.5
 	github.com/stretchr/testify v1.3.0
+	golang.org/x/net v0.2.0
 )
 
 require (
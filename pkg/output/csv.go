@@ -0,0 +1,46 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/liamg/scout/pkg/scan"
+)
+
+// CSVReporter writes results as CSV rows, preceded by a header row when the first result arrives
+type CSVReporter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func NewCSVReporter(w io.Writer) *CSVReporter {
+	return &CSVReporter{writer: csv.NewWriter(w)}
+}
+
+func (r *CSVReporter) ReportURL(result scan.URLResult) error {
+	return r.write(urlColumns, newURLRecord(result).values())
+}
+
+func (r *CSVReporter) ReportVHOST(result scan.VHOSTResult) error {
+	return r.write(vhostColumns, newVHOSTRecord(result).values())
+}
+
+func (r *CSVReporter) write(columns []string, values []string) error {
+	if !r.headerWritten {
+		if err := r.writer.Write(columns); err != nil {
+			return err
+		}
+		r.headerWritten = true
+	}
+	if err := r.writer.Write(values); err != nil {
+		return err
+	}
+	// flush every row so results are visible as they arrive
+	r.writer.Flush()
+	return r.writer.Error()
+}
+
+func (r *CSVReporter) Close() error {
+	r.writer.Flush()
+	return r.writer.Error()
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/liamg/scout/pkg/scan"
+)
+
+// JSONReporter writes results as a single JSON array. Each result is written as it arrives, and the array is closed by Close.
+type JSONReporter struct {
+	w       io.Writer
+	started bool
+}
+
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{w: w}
+}
+
+func (r *JSONReporter) ReportURL(result scan.URLResult) error {
+	return r.write(newURLRecord(result))
+}
+
+func (r *JSONReporter) ReportVHOST(result scan.VHOSTResult) error {
+	return r.write(newVHOSTRecord(result))
+}
+
+func (r *JSONReporter) write(record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if !r.started {
+		separator = "[\n  "
+		r.started = true
+	}
+	if _, err := io.WriteString(r.w, separator); err != nil {
+		return err
+	}
+	_, err = r.w.Write(data)
+	return err
+}
+
+func (r *JSONReporter) Close() error {
+	end := "\n]\n"
+	if !r.started {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(r.w, end)
+	return err
+}
+
+// NDJSONReporter writes each result as a JSON object on its own line, as soon as it arrives
+type NDJSONReporter struct {
+	encoder *json.Encoder
+}
+
+func NewNDJSONReporter(w io.Writer) *NDJSONReporter {
+	return &NDJSONReporter{encoder: json.NewEncoder(w)}
+}
+
+func (r *NDJSONReporter) ReportURL(result scan.URLResult) error {
+	return r.encoder.Encode(newURLRecord(result))
+}
+
+func (r *NDJSONReporter) ReportVHOST(result scan.VHOSTResult) error {
+	return r.encoder.Encode(newVHOSTRecord(result))
+}
+
+func (r *NDJSONReporter) Close() error {
+	return nil
+}
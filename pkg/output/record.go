@@ -0,0 +1,101 @@
+package output
+
+import (
+	"strconv"
+
+	"github.com/liamg/scout/pkg/scan"
+)
+
+// metadataRecord is the structured form of scan.ResponseMetadata, shared by url and vhost records
+type metadataRecord struct {
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	ContentType    string `json:"content_type"`
+	Location       string `json:"location"`
+	Server         string `json:"server"`
+	Words          int    `json:"words"`
+	Lines          int    `json:"lines"`
+	Title          string `json:"title"`
+	BodyHash       string `json:"body_hash"`
+}
+
+var metadataColumns = []string{"response_time_ms", "content_type", "location", "server", "words", "lines", "title", "body_hash"}
+
+func newMetadataRecord(metadata scan.ResponseMetadata) metadataRecord {
+	return metadataRecord{
+		ResponseTimeMs: metadata.ResponseTime.Milliseconds(),
+		ContentType:    metadata.ContentType,
+		Location:       metadata.Location,
+		Server:         metadata.Server,
+		Words:          metadata.Words,
+		Lines:          metadata.Lines,
+		Title:          metadata.Title,
+		BodyHash:       metadata.BodyHash,
+	}
+}
+
+func (r metadataRecord) values() []string {
+	return []string{
+		strconv.FormatInt(r.ResponseTimeMs, 10),
+		r.ContentType,
+		r.Location,
+		r.Server,
+		strconv.Itoa(r.Words),
+		strconv.Itoa(r.Lines),
+		r.Title,
+		r.BodyHash,
+	}
+}
+
+// urlRecord is the structured form of a scan.URLResult
+type urlRecord struct {
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code"`
+	Size       int               `json:"size"`
+	Values     map[string]string `json:"values,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	metadataRecord
+}
+
+var urlColumns = append([]string{"url", "status_code", "size", "values", "source"}, metadataColumns...)
+
+func newURLRecord(result scan.URLResult) urlRecord {
+	return urlRecord{
+		URL:            result.URL.String(),
+		StatusCode:     result.StatusCode,
+		Size:           result.Size,
+		Values:         result.Values,
+		Source:         result.Source,
+		metadataRecord: newMetadataRecord(result.ResponseMetadata),
+	}
+}
+
+func (r urlRecord) values() []string {
+	return append([]string{r.URL, strconv.Itoa(r.StatusCode), strconv.Itoa(r.Size), scan.FormatValues(r.Values), r.Source}, r.metadataRecord.values()...)
+}
+
+// vhostRecord is the structured form of a scan.VHOSTResult
+type vhostRecord struct {
+	VHOST      string `json:"vhost"`
+	IP         string `json:"ip,omitempty"`
+	Origin     string `json:"origin,omitempty"`
+	StatusCode int    `json:"status_code"`
+	Size       int    `json:"size"`
+	metadataRecord
+}
+
+var vhostColumns = append([]string{"vhost", "ip", "origin", "status_code", "size"}, metadataColumns...)
+
+func newVHOSTRecord(result scan.VHOSTResult) vhostRecord {
+	return vhostRecord{
+		VHOST:          result.VHOST,
+		IP:             result.IP,
+		Origin:         result.Origin,
+		StatusCode:     result.StatusCode,
+		Size:           result.Size,
+		metadataRecord: newMetadataRecord(result.ResponseMetadata),
+	}
+}
+
+func (r vhostRecord) values() []string {
+	return append([]string{r.VHOST, r.IP, r.Origin, strconv.Itoa(r.StatusCode), strconv.Itoa(r.Size)}, r.metadataRecord.values()...)
+}
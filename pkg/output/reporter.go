@@ -0,0 +1,43 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liamg/scout/pkg/scan"
+)
+
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{FormatText, FormatJSON, FormatNDJSON, FormatCSV}
+
+// Reporter writes scan results to an output in a particular format. Reporters are not safe for concurrent use.
+type Reporter interface {
+	ReportURL(result scan.URLResult) error
+	ReportVHOST(result scan.VHOSTResult) error
+	// Close finishes the output e.g. closing a JSON array. It does not close the underlying writer.
+	Close() error
+}
+
+// New creates a reporter for the named format
+func New(format string, w io.Writer) (Reporter, error) {
+	switch strings.ToLower(format) {
+	case FormatText:
+		return NewTextReporter(w), nil
+	case FormatJSON:
+		return NewJSONReporter(w), nil
+	case FormatNDJSON:
+		return NewNDJSONReporter(w), nil
+	case FormatCSV:
+		return NewCSVReporter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format '%s' - should be one of: %s", format, strings.Join(Formats, ", "))
+	}
+}
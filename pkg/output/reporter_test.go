@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/liamg/scout/pkg/scan"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testResults(t *testing.T) []scan.URLResult {
+	first, err := url.Parse("http://example.com/login.php")
+	require.NoError(t, err)
+	second, err := url.Parse("http://example.com/admin/")
+	require.NoError(t, err)
+	return []scan.URLResult{
+		{URL: *first, StatusCode: 200, Size: 1234, ResponseMetadata: scan.ResponseMetadata{
+			ResponseTime: 25 * time.Millisecond,
+			ContentType:  "text/html",
+			Words:        120,
+			Lines:        30,
+			Title:        "Log in",
+			BodyHash:     "abc123",
+		}},
+		{URL: *second, StatusCode: 403, Size: 0},
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	reporter, err := New(FormatJSON, buffer)
+	require.NoError(t, err)
+	for _, result := range testResults(t) {
+		require.NoError(t, reporter.ReportURL(result))
+	}
+	require.NoError(t, reporter.Close())
+
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &records))
+	require.Len(t, records, 2)
+	assert.Equal(t, "http://example.com/login.php", records[0]["url"])
+	assert.Equal(t, float64(200), records[0]["status_code"])
+	assert.Equal(t, float64(1234), records[0]["size"])
+	assert.Equal(t, float64(25), records[0]["response_time_ms"])
+	assert.Equal(t, "Log in", records[0]["title"])
+}
+
+func TestJSONReporterWithNoResults(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	reporter := NewJSONReporter(buffer)
+	require.NoError(t, reporter.Close())
+	assert.Equal(t, "[]\n", buffer.String())
+}
+
+func TestNDJSONReporter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	reporter, err := New(FormatNDJSON, buffer)
+	require.NoError(t, err)
+	require.NoError(t, reporter.ReportVHOST(scan.VHOSTResult{VHOST: "admin.example.com", StatusCode: 200}))
+	require.NoError(t, reporter.ReportVHOST(scan.VHOSTResult{VHOST: "dev.example.com", StatusCode: 401}))
+	require.NoError(t, reporter.Close())
+
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.Equal(t, "dev.example.com", record["vhost"])
+	assert.Equal(t, float64(401), record["status_code"])
+}
+
+func TestCSVReporter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	reporter, err := New(FormatCSV, buffer)
+	require.NoError(t, err)
+	for _, result := range testResults(t) {
+		require.NoError(t, reporter.ReportURL(result))
+	}
+	require.NoError(t, reporter.Close())
+
+	assert.Equal(t, "url,status_code,size,values,source,response_time_ms,content_type,location,server,words,lines,title,body_hash\n"+
+		"http://example.com/login.php,200,1234,,,25,text/html,,,120,30,Log in,abc123\n"+
+		"http://example.com/admin/,403,0,,,0,,,,0,0,,\n", buffer.String())
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := New("xml", bytes.NewBuffer(nil))
+	assert.Error(t, err)
+}
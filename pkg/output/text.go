@@ -0,0 +1,67 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/liamg/scout/pkg/scan"
+)
+
+// TextReporter writes results as plain lines, in the same layout as the CLI but without colours
+type TextReporter struct {
+	w io.Writer
+}
+
+func NewTextReporter(w io.Writer) *TextReporter {
+	return &TextReporter{w: w}
+}
+
+func (r *TextReporter) ReportURL(result scan.URLResult) error {
+	_, err := fmt.Fprintf(r.w, "[%d] [%d] %s%s\n", result.StatusCode, result.Size, DescribeURL(result), describe(result.ResponseMetadata))
+	return err
+}
+
+func (r *TextReporter) ReportVHOST(result scan.VHOSTResult) error {
+	_, err := fmt.Fprintf(r.w, "[%d] [%d] %s%s\n", result.StatusCode, result.Size, describeVHOST(result), describe(result.ResponseMetadata))
+	return err
+}
+
+// describeVHOST includes the origin the vhost was found on, if known
+func describeVHOST(result scan.VHOSTResult) string {
+	switch {
+	case result.Origin != "":
+		return result.VHOST + " on " + result.Origin
+	case result.IP != "":
+		return result.VHOST + " on " + result.IP
+	}
+	return result.VHOST
+}
+
+// DescribeURL includes the words which were substituted into the request, when it cannot be seen in the url, and where
+// the url was discovered
+func DescribeURL(result scan.URLResult) string {
+	description := result.URL.String()
+	if len(result.Values) > 0 {
+		description += " " + scan.FormatValues(result.Values)
+	}
+	if result.Source != "" {
+		description += " [" + result.Source + "]"
+	}
+	return description
+}
+
+// describe summarises the most useful metadata for display after a result
+func describe(metadata scan.ResponseMetadata) string {
+	var description string
+	if metadata.Location != "" {
+		description += " -> " + metadata.Location
+	}
+	if metadata.Title != "" {
+		description += " (" + metadata.Title + ")"
+	}
+	return description
+}
+
+func (r *TextReporter) Close() error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package scan
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// the most addresses a CIDR range may expand to, each of which is probed on every port
+const maxRangeBits = 12
+
+// expandIPs parses IP addresses and CIDR ranges, returning each distinct address
+func expandIPs(inputs []string) ([]net.IP, error) {
+
+	var ips []net.IP
+	seen := make(map[string]bool)
+
+	add := func(ip net.IP) {
+		if !seen[ip.String()] {
+			seen[ip.String()] = true
+			ips = append(ips, ip)
+		}
+	}
+
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if !strings.Contains(input, "/") {
+			ip := net.ParseIP(strings.Trim(input, "[]"))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address specified: %s", input)
+			}
+			add(ip)
+			continue
+		}
+		ip, network, err := net.ParseCIDR(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP range specified: %s", input)
+		}
+		if ip.To4() != nil {
+			network.IP = network.IP.To4()
+		}
+		ones, size := network.Mask.Size()
+		if size-ones > maxRangeBits {
+			return nil, fmt.Errorf("IP range %s is too large: at most %d addresses may be scanned", input, 1<<maxRangeBits)
+		}
+		// the network and broadcast addresses of an IPv4 range are not hosts, except in a /31 or /32 which has no room for them
+		hostsOnly := size == 32 && size-ones > 1
+		for ip := network.IP; network.Contains(ip); ip = nextIP(ip) {
+			if hostsOnly && (ip.Equal(network.IP) || !network.Contains(nextIP(ip))) {
+				continue
+			}
+			add(ip)
+		}
+	}
+
+	return ips, nil
+}
+
+// nextIP returns the address after ip, which is zero if ip is the last
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
@@ -0,0 +1,40 @@
+package scan
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandIPs(t *testing.T) {
+
+	ips, err := expandIPs([]string{"10.0.0.5", "10.0.0.0/29", "10.0.1.0/31", "10.0.2.7/32", "[::1]", "2001:db8::/127"})
+	require.NoError(t, err)
+
+	var addresses []string
+	for _, ip := range ips {
+		addresses = append(addresses, ip.String())
+	}
+	assert.Equal(t, []string{
+		"10.0.0.5", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.6",
+		"10.0.1.0", "10.0.1.1", "10.0.2.7", "::1", "2001:db8::", "2001:db8::1",
+	}, addresses)
+
+	ips, err = expandIPs([]string{"10.0.0.0/20"})
+	require.NoError(t, err)
+	assert.Equal(t, 4094, len(ips))
+}
+
+func TestExpandIPsInvalid(t *testing.T) {
+	for _, input := range []string{"nope", "10.0.0.300", "10.0.0.0/33", "10.0.0.0/19", "10.0.0.0/8", "2001:db8::/115"} {
+		_, err := expandIPs([]string{input})
+		assert.Error(t, err, input)
+	}
+}
+
+func TestNextIP(t *testing.T) {
+	assert.Equal(t, "10.0.1.0", nextIP(net.ParseIP("10.0.0.255").To4()).String())
+	assert.Equal(t, "0.0.0.0", nextIP(net.ParseIP("255.255.255.255").To4()).String())
+}
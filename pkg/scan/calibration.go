@@ -0,0 +1,215 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// how many random paths to request for each variation (no extension, each extension) during calibration
+const calibrationAttempts = 3
+
+// Baseline describes the response a server gives for a path which does not exist
+type Baseline struct {
+	StatusCode int
+	Size       int // not counting reflections of the requested path or substituted words
+	Words      int
+	Location   string // redirect target, with the requested path replaced by {path}
+}
+
+func (b Baseline) String() string {
+	str := fmt.Sprintf("[%d] size=%d words=%d", b.StatusCode, b.Size, b.Words)
+	if b.Location != "" {
+		str += " -> " + b.Location
+	}
+	return str
+}
+
+func (b Baseline) matches(code int, size int, words int, location string) bool {
+	return b.StatusCode == code && b.Location == location && b.Size == size && b.Words == words
+}
+
+// Calibrate requests random non-existent paths below the target url (or substitutes random words into a templated
+// request) and returns the distinct responses seen.
+// Scan will calibrate automatically if auto-calibration is enabled and Calibrate has not already been called.
+func (scanner *URLScanner) Calibrate() ([]Baseline, error) {
+	return scanner.calibrate(context.Background(), scanner.prefix())
+}
+
+func (scanner *URLScanner) calibrate(ctx context.Context, prefix string) ([]Baseline, error) {
+
+	scanner.baselineMutex.Lock()
+	if baselines, ok := scanner.baselines[prefix]; ok {
+		scanner.baselineMutex.Unlock()
+		return baselines, nil
+	}
+	scanner.baselineMutex.Unlock()
+
+	logrus.Debugf("Calibrating %s...", prefix)
+
+	var baselines []Baseline
+
+	for i := 0; i < calibrationAttempts; i++ {
+		word, err := randomWord()
+		if err != nil {
+			return nil, err
+		}
+		var jobs []URLJob
+		if scanner.templated() {
+			values := make(map[string]string)
+			for _, keyword := range scanner.keywords() {
+				values[keyword] = word
+			}
+			jobs = scanner.templateJobs(values)
+		} else {
+			paths := []string{word}
+			if scanner.filename != "" {
+				paths = append(paths, word+"/"+scanner.filename)
+			}
+			for _, ext := range scanner.extensions {
+				paths = append(paths, word+"."+ext)
+			}
+			for _, path := range paths {
+				jobs = append(jobs, URLJob{URL: prefix + path})
+			}
+		}
+		for _, job := range jobs {
+			baseline, err := scanner.fetchBaseline(ctx, job)
+			if err != nil {
+				return nil, err
+			}
+			if !containsBaseline(baselines, baseline) {
+				baselines = append(baselines, baseline)
+			}
+		}
+	}
+
+	scanner.baselineMutex.Lock()
+	defer scanner.baselineMutex.Unlock()
+	scanner.baselines[prefix] = baselines
+
+	return baselines, nil
+}
+
+func (scanner *URLScanner) fetchBaseline(ctx context.Context, job URLJob) (Baseline, error) {
+
+	req, err := scanner.newRequest(ctx, job)
+	if err != nil {
+		return Baseline{}, err
+	}
+
+	resp, err := scanner.limiter.do(scanner.client, req, req.URL.Host)
+	if err != nil {
+		return Baseline{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, size, err := readBody(resp)
+	if err != nil {
+		return Baseline{}, err
+	}
+
+	return Baseline{
+		StatusCode: resp.StatusCode,
+		Size:       unreflectedSize(body, size, req.URL, job.Values),
+		Words:      len(bytes.Fields(body)),
+		Location:   normaliseLocation(req.URL, resp.Header.Get("Location")),
+	}, nil
+}
+
+// matchesBaseline returns true if the response looks like the response to a non-existent path in the same directory
+func (scanner *URLScanner) matchesBaseline(target *url.URL, values map[string]string, code int, body []byte, size int, words int, location string) bool {
+
+	scanner.baselineMutex.Lock()
+	baselines, ok := scanner.baselines[scanner.baselineDirectory(target)]
+	if !ok {
+		baselines = scanner.baselines[scanner.prefix()]
+	}
+	scanner.baselineMutex.Unlock()
+
+	location = normaliseLocation(target, location)
+	size = unreflectedSize(body, size, target, values)
+
+	for _, baseline := range baselines {
+		if baseline.matches(code, size, words, location) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// baselineDirectory returns the directory which was calibrated for a path, i.e. the one the word was appended to
+func (scanner *URLScanner) baselineDirectory(target *url.URL) string {
+	dir := *target
+	dir.RawQuery = ""
+	dir.Fragment = ""
+	if scanner.filename != "" && !scanner.templated() && strings.HasSuffix(dir.Path, "/"+scanner.filename) {
+		// the word is a directory containing the filename, so the calibrated directory is the one above it
+		dir.Path = strings.TrimSuffix(dir.Path, "/"+scanner.filename)
+	}
+	dir.Path = dir.Path[:strings.LastIndex(dir.Path, "/")+1]
+	return dir.String()
+}
+
+// normaliseLocation resolves a redirect target and replaces the requested path so that redirects can be compared across paths
+func normaliseLocation(target *url.URL, location string) string {
+	if location == "" {
+		return ""
+	}
+	relative, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	resolved := target.ResolveReference(relative).String()
+	if target.Path == "" || target.Path == "/" {
+		return resolved
+	}
+	resolved = strings.ReplaceAll(resolved, url.QueryEscape(target.Path), "{path}")
+	return strings.ReplaceAll(resolved, target.Path, "{path}")
+}
+
+// unreflectedSize returns the size of a body without any reflections of the requested path or substituted words, so
+// that pages which echo what was requested can be compared across requests
+func unreflectedSize(body []byte, size int, target *url.URL, values map[string]string) int {
+	reflected := []string{strings.TrimPrefix(target.Path, "/"), path.Base(target.Path)}
+	for _, value := range values {
+		reflected = append(reflected, value)
+	}
+	// longest first, so that the path is removed before the name within it
+	sort.Slice(reflected, func(i, j int) bool { return len(reflected[i]) > len(reflected[j]) })
+	for _, value := range reflected {
+		if len(value) <= 1 {
+			continue
+		}
+		needle := []byte(value)
+		size -= bytes.Count(body, needle) * len(needle)
+		body = bytes.ReplaceAll(body, needle, nil)
+	}
+	return size
+}
+
+func containsBaseline(baselines []Baseline, baseline Baseline) bool {
+	for _, existing := range baselines {
+		if existing == baseline {
+			return true
+		}
+	}
+	return false
+}
+
+func randomWord() (string, error) {
+	data := make([]byte, 8)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
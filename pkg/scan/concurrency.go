@@ -0,0 +1,153 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// AdaptiveConcurrency lets a scanner choose how many requests to have in flight, between Min and Max. The level grows
+// by one each time as many requests as the current level succeed, and halves when requests fail, time out, are
+// throttled, or take more than twice as long as usual. The zero value disables it.
+type AdaptiveConcurrency struct {
+	Min int
+	Max int
+}
+
+func (a AdaptiveConcurrency) enabled() bool {
+	return a.Max > 0
+}
+
+const (
+	latencySmoothing = 0.1              // weight of each new response time in the running average
+	latencyRelaxing  = 0.01             // how quickly the usual response time follows a server which has slowed down for good
+	latencySlowdown  = 2.0              // how many times slower than usual responses must be to count as congestion
+	decreaseCooldown = time.Second      // requests in flight together tend to fail together, so only one of them counts
+	minimumLatency   = time.Millisecond // stops very fast local servers from looking congested by a millisecond of noise
+)
+
+// concurrency limits how many requests are in flight, adjusting the limit from how the requests fare
+type concurrency struct {
+	mutex        sync.Mutex
+	min          int
+	max          int
+	limit        int
+	active       int
+	successes    int           // since the limit last changed
+	smoothed     time.Duration // running average response time
+	usual        time.Duration // lowest running average, relaxing slowly upwards
+	lastDecrease time.Time
+	changed      chan struct{} // closed and replaced whenever a slot may have become free
+}
+
+func newConcurrency(adaptive AdaptiveConcurrency, initial int) *concurrency {
+	min, max := adaptive.Min, adaptive.Max
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if initial < min {
+		initial = min
+	}
+	if initial > max {
+		initial = max
+	}
+	return &concurrency{
+		min:     min,
+		max:     max,
+		limit:   initial,
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire waits for a slot to send a request in. A nil concurrency never waits.
+func (c *concurrency) acquire(ctx context.Context) error {
+	if c == nil {
+		return ctx.Err()
+	}
+	for {
+		c.mutex.Lock()
+		if c.active < c.limit {
+			c.active++
+			c.mutex.Unlock()
+			return ctx.Err()
+		}
+		changed := c.changed
+		c.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// release frees a slot taken by acquire, adjusting the limit according to how the request fared - err is non-nil if
+// it failed or was throttled
+func (c *concurrency) release(latency time.Duration, err error) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.active--
+	defer c.notify()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		// the scan is stopping, which says nothing about the server
+	case err != nil:
+		c.decrease()
+	default:
+		if latency < minimumLatency {
+			latency = minimumLatency
+		}
+		if c.smoothed == 0 {
+			c.smoothed, c.usual = latency, latency
+		} else {
+			c.smoothed += time.Duration(float64(latency-c.smoothed) * latencySmoothing)
+		}
+		if c.smoothed < c.usual {
+			c.usual = c.smoothed
+		} else {
+			c.usual += time.Duration(float64(c.smoothed-c.usual) * latencyRelaxing)
+		}
+		if float64(c.smoothed) > float64(c.usual)*latencySlowdown {
+			c.decrease()
+			return
+		}
+		c.successes++
+		if c.successes >= c.limit && c.limit < c.max {
+			c.limit++
+			c.successes = 0
+		}
+	}
+}
+
+func (c *concurrency) decrease() {
+	if time.Since(c.lastDecrease) < decreaseCooldown {
+		return
+	}
+	c.lastDecrease = time.Now()
+	c.successes = 0
+	c.limit /= 2
+	if c.limit < c.min {
+		c.limit = c.min
+	}
+}
+
+func (c *concurrency) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// level returns the current limit
+func (c *concurrency) level() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.limit
+}
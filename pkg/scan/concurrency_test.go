@@ -0,0 +1,168 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyBounds(t *testing.T) {
+	c := newConcurrency(AdaptiveConcurrency{Min: 2, Max: 8}, 20)
+	assert.Equal(t, 8, c.level())
+
+	c = newConcurrency(AdaptiveConcurrency{Max: 8}, 0)
+	assert.Equal(t, 1, c.level())
+}
+
+func TestConcurrencyIncreasesAdditively(t *testing.T) {
+
+	c := newConcurrency(AdaptiveConcurrency{Min: 1, Max: 4}, 2)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, c.acquire(context.Background()))
+		c.release(time.Millisecond*10, nil)
+	}
+	assert.Equal(t, 3, c.level())
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, c.acquire(context.Background()))
+		c.release(time.Millisecond*10, nil)
+	}
+	assert.Equal(t, 4, c.level())
+}
+
+func TestConcurrencyDecreasesMultiplicatively(t *testing.T) {
+
+	c := newConcurrency(AdaptiveConcurrency{Min: 2, Max: 16}, 16)
+
+	require.NoError(t, c.acquire(context.Background()))
+	c.release(0, errors.New("timeout"))
+	assert.Equal(t, 8, c.level())
+
+	// failures of requests which were in flight at the same time only count once
+	require.NoError(t, c.acquire(context.Background()))
+	c.release(0, errors.New("timeout"))
+	assert.Equal(t, 8, c.level())
+
+	for i := 0; i < 3; i++ {
+		c.lastDecrease = time.Time{}
+		require.NoError(t, c.acquire(context.Background()))
+		c.release(0, errors.New("timeout"))
+	}
+	assert.Equal(t, 2, c.level())
+
+	// a cancelled scan says nothing about the server
+	c.lastDecrease = time.Time{}
+	c.limit = 4
+	require.NoError(t, c.acquire(context.Background()))
+	c.release(0, context.Canceled)
+	assert.Equal(t, 4, c.level())
+}
+
+func TestConcurrencyDecreasesWhenSlow(t *testing.T) {
+
+	c := newConcurrency(AdaptiveConcurrency{Min: 1, Max: 16}, 16)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.acquire(context.Background()))
+		c.release(time.Millisecond*10, nil)
+	}
+	assert.Equal(t, 16, c.level())
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.acquire(context.Background()))
+		c.release(time.Second, nil)
+	}
+	assert.Equal(t, 8, c.level())
+}
+
+func TestConcurrencyLimitsRequestsInFlight(t *testing.T) {
+
+	c := newConcurrency(AdaptiveConcurrency{Min: 1, Max: 1}, 1)
+	require.NoError(t, c.acquire(context.Background()))
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = c.acquire(context.Background())
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired a second slot at a level of one")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	c.release(time.Millisecond, nil)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("slot was not freed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, c.acquire(ctx))
+}
+
+func TestURLScannerWithAdaptiveConcurrency(t *testing.T) {
+
+	var inFlight, highest int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&highest)
+			if current <= seen || atomic.CompareAndSwapInt32(&highest, seen, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 5)
+		if r.URL.Path == "/login.php" {
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	var words bytes.Buffer
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&words, "page%d\n", i)
+	}
+	words.WriteString("login\n")
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithWordlist(wordlist.FromReader(&words)),
+		WithExtensions([]string{"php"}),
+		WithParallelism(2),
+		WithAdaptiveConcurrency(AdaptiveConcurrency{Min: 1, Max: 4}),
+		WithSeeding(false),
+	)
+
+	assert.Equal(t, 2, scanner.Concurrency())
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/login.php", results[0].String())
+
+	assert.True(t, atomic.LoadInt32(&highest) <= 4, "%d requests were in flight", highest)
+	assert.True(t, scanner.Concurrency() >= 1 && scanner.Concurrency() <= 4)
+}
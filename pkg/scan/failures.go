@@ -0,0 +1,147 @@
+package scan
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorCategory groups the ways a request can fail to get a response
+type ErrorCategory string
+
+const (
+	ErrorTimeout   ErrorCategory = "timeout"
+	ErrorRefused   ErrorCategory = "connection refused"
+	ErrorReset     ErrorCategory = "connection reset"
+	ErrorDNS       ErrorCategory = "dns"
+	ErrorTLS       ErrorCategory = "tls"
+	ErrorThrottled ErrorCategory = "throttled" // still 429 or 503 after the last retry
+	ErrorOther     ErrorCategory = "other"
+)
+
+// ScanError describes a request which failed without a usable response, after any retries
+type ScanError struct {
+	URL      string
+	Category ErrorCategory
+	Err      error
+}
+
+func (e ScanError) Error() string {
+	return fmt.Sprintf("%s: %s", e.URL, e.Err)
+}
+
+func (e ScanError) Unwrap() error {
+	return e.Err
+}
+
+// ErrTargetUnreachable is returned by a scan which was aborted because too many requests in a row failed
+var ErrTargetUnreachable = errors.New("target unreachable")
+
+// classifyError decides which category a failed request belongs to
+func classifyError(err error) ErrorCategory {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case errors.Is(err, errThrottled):
+		return ErrorThrottled
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorReset
+	case errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr),
+		strings.Contains(err.Error(), "tls: "), strings.Contains(err.Error(), "x509: "):
+		return ErrorTLS
+	}
+	return ErrorOther
+}
+
+// failures counts requests which failed without a response, and aborts the scan once too many fail in a row
+type failures struct {
+	mutex       sync.Mutex
+	counts      map[ErrorCategory]int
+	consecutive int
+	budget      int // how many requests in a row may fail - 0 is unlimited
+	events      chan<- ScanError
+	cancel      context.CancelFunc
+	exceeded    error
+}
+
+func newFailures(budget int, events chan<- ScanError) *failures {
+	return &failures{
+		counts: make(map[ErrorCategory]int),
+		budget: budget,
+		events: events,
+	}
+}
+
+// start records how to abort the scan which is about to run
+func (f *failures) start(cancel context.CancelFunc) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.cancel = cancel
+	f.consecutive = 0
+	f.exceeded = nil
+}
+
+// fail records a request which failed. Events are sent without blocking, so are dropped if nobody is listening.
+func (f *failures) fail(uri string, err error) {
+	scanErr := ScanError{URL: uri, Category: classifyError(err), Err: err}
+	logrus.Debugf("Request failed (%s): %s", scanErr.Category, scanErr)
+
+	f.mutex.Lock()
+	f.counts[scanErr.Category]++
+	f.consecutive++
+	if f.budget > 0 && f.consecutive >= f.budget && f.exceeded == nil {
+		f.exceeded = fmt.Errorf("%w: the last %d requests failed, most recently: %s", ErrTargetUnreachable, f.consecutive, err)
+		if f.cancel != nil {
+			f.cancel()
+		}
+	}
+	f.mutex.Unlock()
+
+	if f.events != nil {
+		select {
+		case f.events <- scanErr:
+		default:
+		}
+	}
+}
+
+// succeed records a request which got a response
+func (f *failures) succeed() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.consecutive = 0
+}
+
+// err returns the reason the scan was aborted, if it was
+func (f *failures) err() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.exceeded
+}
+
+func (f *failures) snapshot() map[ErrorCategory]int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	counts := make(map[ErrorCategory]int, len(f.counts))
+	for category, count := range f.counts {
+		counts[category] = count
+	}
+	return counts
+}
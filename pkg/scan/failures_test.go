@@ -0,0 +1,137 @@
+package scan
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	_, refused := net.Dial("tcp", closed)
+	require.Error(t, refused)
+
+	tests := []struct {
+		err      error
+		category ErrorCategory
+	}{
+		{err: refused, category: ErrorRefused},
+		{err: &url.Error{Op: "Get", URL: "http://example.com", Err: timeoutError{}}, category: ErrorTimeout},
+		{err: fmt.Errorf("waiting: %w", context.DeadlineExceeded), category: ErrorTimeout},
+		{err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "nope.invalid"}}, category: ErrorDNS},
+		{err: &url.Error{Op: "Get", URL: "http://example.com", Err: io.EOF}, category: ErrorReset},
+		{err: &url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, category: ErrorTLS},
+		{err: errors.New("tls: handshake failure"), category: ErrorTLS},
+		{err: fmt.Errorf("%w: example.com responded with 503", errThrottled), category: ErrorThrottled},
+		{err: errors.New("something else"), category: ErrorOther},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.category, classifyError(test.err), test.err.Error())
+	}
+}
+
+func TestFailuresBudget(t *testing.T) {
+
+	f := newFailures(3, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.start(cancel)
+
+	f.fail("http://example.com/a", io.EOF)
+	f.fail("http://example.com/b", io.EOF)
+	f.succeed()
+	f.fail("http://example.com/c", io.EOF)
+	f.fail("http://example.com/d", io.EOF)
+	require.NoError(t, f.err())
+	assert.NoError(t, ctx.Err())
+
+	f.fail("http://example.com/e", io.EOF)
+	assert.True(t, errors.Is(f.err(), ErrTargetUnreachable))
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Equal(t, map[ErrorCategory]int{ErrorReset: 5}, f.snapshot())
+}
+
+func TestURLScannerErrorBudget(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	target, err := url.Parse("http://" + listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	errorChan := make(chan ScanError, 100)
+	scanner := NewURLScanner(
+		WithTargetURL(*target),
+		WithParallelism(1),
+		WithSeeding(false),
+		WithErrorChan(errorChan),
+		WithErrorBudget(10),
+	)
+
+	_, err = scanner.Scan()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrTargetUnreachable), err.Error())
+	assert.Equal(t, map[ErrorCategory]int{ErrorRefused: 10}, scanner.Errors())
+
+	require.Equal(t, 10, len(errorChan))
+	failed := <-errorChan
+	assert.Equal(t, ErrorRefused, failed.Category)
+}
+
+func TestURLScannerCountsPersistentThrottling(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	errorChan := make(chan ScanError, 100)
+	scanner := NewURLScanner(
+		WithTargetURL(*target),
+		WithParallelism(1),
+		WithSeeding(false),
+		WithWordlist(wordlist.FromReader(strings.NewReader("admin"))),
+		WithExtensions(nil),
+		WithIncludeNoExtension(true),
+		WithBackupExtensions(nil),
+		WithErrorChan(errorChan),
+		WithRetryPolicy(RetryPolicy{Attempts: 1, Retry: []RetryCondition{RetryThrottled}}),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(results))
+	assert.Equal(t, map[ErrorCategory]int{ErrorThrottled: 2}, scanner.Errors())
+
+	require.Equal(t, 2, len(errorChan))
+	failed := <-errorChan
+	assert.Equal(t, server.URL+"/", failed.URL)
+	assert.Equal(t, ErrorThrottled, failed.Category)
+}
@@ -0,0 +1,132 @@
+package scan
+
+import "sync"
+
+// priority decides the order in which queued jobs are checked, highest first
+type priority int
+
+const (
+	priorityWord   priority = iota // words from the wordlist
+	priorityBackup                 // backup copies of files which were found
+	priorityLink                   // links found by spidering, seeding and following redirects
+	priorityCount
+)
+
+func jobPriority(job URLJob) priority {
+	switch job.Source {
+	case "":
+		return priorityWord
+	case "backup":
+		return priorityBackup
+	}
+	return priorityLink
+}
+
+// frontier holds the jobs waiting to be checked. Jobs discovered while checking others can always be added without
+// blocking, however many there are, and are taken ahead of words from the wordlist.
+type frontier struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	queues  [priorityCount][]URLJob // first in, first out within each priority
+	size    int
+	active  int  // jobs which have been taken but not yet finished, which may still discover more
+	closed  bool // set once the loader has added every job
+	stopped bool // set when the scan is cancelled
+}
+
+func newFrontier() *frontier {
+	f := &frontier{}
+	f.cond = sync.NewCond(&f.mutex)
+	return f
+}
+
+// push adds jobs without blocking
+func (f *frontier) push(jobs []URLJob) {
+	if len(jobs) == 0 {
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, job := range jobs {
+		p := jobPriority(job)
+		f.queues[p] = append(f.queues[p], job)
+	}
+	f.size += len(jobs)
+	f.cond.Broadcast()
+}
+
+// pushWait adds jobs once fewer than limit are waiting, so that the wordlist is read no faster than it is checked. It
+// returns false if the scan was cancelled first.
+func (f *frontier) pushWait(jobs []URLJob, limit int) bool {
+	f.mutex.Lock()
+	for f.size >= limit && !f.stopped {
+		f.cond.Wait()
+	}
+	stopped := f.stopped
+	f.mutex.Unlock()
+	if stopped {
+		return false
+	}
+	f.push(jobs)
+	return true
+}
+
+// next waits for the highest priority job. It returns false once there is nothing left to do - the loader has
+// finished, nothing is queued and no job in progress can discover more - or the scan was cancelled. Every job taken
+// must be passed to finish once its discoveries have been pushed.
+func (f *frontier) next() (URLJob, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for !f.stopped {
+		for p := priorityCount - 1; p >= 0; p-- {
+			if len(f.queues[p]) > 0 {
+				job := f.queues[p][0]
+				f.queues[p][0] = URLJob{}
+				f.queues[p] = f.queues[p][1:]
+				f.size--
+				f.active++
+				f.cond.Broadcast()
+				return job, true
+			}
+		}
+		if f.closed && f.active == 0 {
+			return URLJob{}, false
+		}
+		f.cond.Wait()
+	}
+	return URLJob{}, false
+}
+
+// finish records that a job taken by next has been processed
+func (f *frontier) finish() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.active--
+	f.cond.Broadcast()
+}
+
+// waitIdle waits until nothing is queued or in progress. It returns false if the scan was cancelled first.
+func (f *frontier) waitIdle() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for (f.size > 0 || f.active > 0) && !f.stopped {
+		f.cond.Wait()
+	}
+	return !f.stopped
+}
+
+// close records that the loader will add no more jobs, so workers can stop once everything else is done
+func (f *frontier) close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.closed = true
+	f.cond.Broadcast()
+}
+
+// stop wakes everything waiting on the frontier when the scan is cancelled
+func (f *frontier) stop() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.stopped = true
+	f.cond.Broadcast()
+}
@@ -0,0 +1,133 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontierPriority(t *testing.T) {
+
+	f := newFrontier()
+	f.push([]URLJob{{URL: "/word1"}, {URL: "/word1.bak", Source: "backup"}, {URL: "/word2"}})
+	f.push([]URLJob{{URL: "/link", Source: "html:a[href]"}, {URL: "/redirect", Source: "redirect"}})
+	f.close()
+
+	var order []string
+	for {
+		job, ok := f.next()
+		if !ok {
+			break
+		}
+		order = append(order, job.URL)
+		f.finish()
+	}
+
+	assert.Equal(t, []string{"/link", "/redirect", "/word1.bak", "/word1", "/word2"}, order)
+}
+
+func TestFrontierWaitsForActiveJobs(t *testing.T) {
+
+	f := newFrontier()
+	f.push([]URLJob{{URL: "/"}})
+	f.close()
+
+	job, ok := f.next()
+	require.True(t, ok)
+	assert.Equal(t, "/", job.URL)
+
+	// the frontier is empty, but the job in progress may still discover more
+	taken := make(chan URLJob)
+	go func() {
+		job, ok := f.next()
+		if ok {
+			f.finish()
+		}
+		taken <- job
+		_, ok = f.next()
+		assert.False(t, ok)
+		close(taken)
+	}()
+
+	f.push([]URLJob{{URL: "/discovered", Source: "html:a[href]"}})
+	f.finish()
+
+	assert.Equal(t, "/discovered", (<-taken).URL)
+	_, open := <-taken
+	assert.False(t, open)
+}
+
+func TestFrontierStop(t *testing.T) {
+
+	f := newFrontier()
+	stopped := make(chan bool)
+	go func() {
+		_, ok := f.next()
+		stopped <- ok
+	}()
+
+	f.stop()
+
+	select {
+	case ok := <-stopped:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("next did not return after the frontier was stopped")
+	}
+	assert.False(t, f.pushWait([]URLJob{{URL: "/"}}, 0))
+	assert.False(t, f.waitIdle())
+}
+
+func TestURLScannerSpidersLargeSites(t *testing.T) {
+
+	// far more links than workers could ever hold, all discovered by a single page
+	const links = 5000
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		var page strings.Builder
+		for i := 0; i < links; i++ {
+			_, _ = fmt.Fprintf(&page, `<a href="/page%d">%d</a>`, i, i)
+		}
+		_, _ = w.Write([]byte(page.String()))
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(1),
+		WithWordlist(wordlist.FromReader(bytes.NewReader(nil))),
+		WithSpidering(true),
+	)
+
+	done := make(chan error)
+	go func() {
+		_, err := scanner.Scan()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Minute):
+		t.Fatal("scan did not complete")
+	}
+	assert.Equal(t, 0, len(scanner.State().Queue))
+}
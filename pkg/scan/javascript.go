@@ -0,0 +1,216 @@
+package scan
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// string literals, including template literals, which are not split across lines
+	jsLiteralRegex = regexp.MustCompile(`"((?:[^"\\\n]|\\.){2,})"|'((?:[^'\\\n]|\\.){2,})'|` + "`([^`\\\\]{2,})`")
+	// calls which make requests, so that strings passed to them can be tagged with where they were found
+	jsCalls = []struct {
+		name    string
+		pattern *regexp.Regexp
+	}{
+		{"fetch", regexp.MustCompile(`\bfetch\(\s*$`)},
+		{"axios", regexp.MustCompile(`\baxios(?:\.(?:get|post|put|patch|delete|head|options|request))?\(\s*$`)},
+		{"xhr", regexp.MustCompile(`\.open\(\s*["'][A-Za-z]+["']\s*,\s*$`)},
+		{"ajax", regexp.MustCompile(`\$\.(?:ajax|get|post|getJSON)\(\s*$|\burl\s*:\s*$`)},
+	}
+	jsRelativePathRegex = regexp.MustCompile(`^[A-Za-z0-9_\-~.]+(?:/[A-Za-z0-9_\-~.:{}$]+)+/?(?:\?[^\s]*)?$`)
+	jsExtensionRegex    = regexp.MustCompile(`\.[A-Za-z0-9]{1,5}$`)
+	mimeTypeRegex       = regexp.MustCompile(`^(?:application|audio|font|image|message|model|multipart|text|video)/`)
+	sourceMapRegex      = regexp.MustCompile(`(?m)^\s*//[#@]\s*sourceMappingURL=(\S+)\s*$`)
+)
+
+// spider finds links in a response, according to what kind of content it contains
+func spider(currentURL string, header http.Header, contentType string, body []byte) []Link {
+
+	target, err := url.Parse(currentURL)
+	if err != nil {
+		return nil
+	}
+	ext := strings.ToLower(path.Ext(target.Path))
+
+	switch {
+	case ext == ".map":
+		return findSourceMapLinks(currentURL, body)
+	case ext == ".js" || ext == ".mjs" || strings.Contains(contentType, "javascript") || strings.Contains(contentType, "ecmascript"):
+		links := findJSLinks(currentURL, body)
+		if mapping := sourceMapReference(header, body); mapping != "" {
+			links = append(links, resolveLinks(target, []Link{{URL: mapping, Source: "js:sourcemap"}})...)
+		}
+		return links
+	case contentType == "" || strings.Contains(contentType, "html"):
+		return findLinks(currentURL, body)
+	}
+
+	return nil
+}
+
+// findJSLinks extracts urls and paths from the string literals in a script
+func findJSLinks(currentURL string, body []byte) []Link {
+	base, err := url.Parse(currentURL)
+	if err != nil {
+		return nil
+	}
+	extractor := &linkExtractor{}
+	extractor.extractJS(body, "js")
+	return resolveLinks(base, extractor.links)
+}
+
+// extractJS adds every string literal which looks like a url or path. Those passed directly to fetch, axios, XHR or
+// jQuery are tagged as such e.g. "js:fetch", and the rest as strings e.g. "js:string".
+func (e *linkExtractor) extractJS(js []byte, prefix string) {
+	for _, match := range jsLiteralRegex.FindAllSubmatchIndex(js, -1) {
+		var literal string
+		for group := 1; group <= 3; group++ {
+			if start := match[group*2]; start >= 0 {
+				literal = string(js[start:match[group*2+1]])
+				break
+			}
+		}
+		link, ok := jsEndpoint(literal)
+		if !ok {
+			continue
+		}
+		source := prefix + ":string"
+		preceding := js[:match[0]]
+		if len(preceding) > 64 {
+			preceding = preceding[len(preceding)-64:]
+		}
+		for _, call := range jsCalls {
+			if call.pattern.Match(preceding) {
+				source = prefix + ":" + call.name
+				break
+			}
+		}
+		e.add(link, source)
+	}
+}
+
+// jsEndpoint returns the path a string literal refers to, if it looks like one. Route parameters such as :id or
+// ${id} are trimmed, along with everything after them.
+func jsEndpoint(literal string) (string, bool) {
+
+	literal = strings.TrimSpace(strings.ReplaceAll(literal, `\/`, "/"))
+	if literal == "" || strings.ContainsAny(literal, " \t<>\"'") || mimeTypeRegex.MatchString(literal) {
+		return "", false
+	}
+
+	switch {
+	case strings.HasPrefix(literal, "http://"), strings.HasPrefix(literal, "https://"):
+	case strings.HasPrefix(literal, "//"):
+	case strings.HasPrefix(literal, "/"), strings.HasPrefix(literal, "./"), strings.HasPrefix(literal, "../"):
+		if literal == "/" || literal == "./" || literal == "../" {
+			return "", false
+		}
+	default:
+		// bare relative paths are common in ordinary strings, so only take those which look like files or api routes
+		if !jsRelativePathRegex.MatchString(literal) {
+			return "", false
+		}
+		if !jsExtensionRegex.MatchString(strings.SplitN(literal, "?", 2)[0]) && !strings.HasPrefix(strings.ToLower(literal), "api/") {
+			return "", false
+		}
+	}
+
+	for _, marker := range []string{"${", "/:", "{"} {
+		if index := strings.Index(literal, marker); index >= 0 {
+			literal = literal[:index]
+			if marker == "/:" {
+				literal += "/"
+			}
+		}
+	}
+
+	if literal == "" || literal == "/" {
+		return "", false
+	}
+
+	return literal, true
+}
+
+// sourceMapReference returns the source map url referenced by a script, if any
+func sourceMapReference(header http.Header, body []byte) string {
+	if mapping := header.Get("SourceMap"); mapping != "" {
+		return mapping
+	}
+	if mapping := header.Get("X-SourceMap"); mapping != "" {
+		return mapping
+	}
+	matches := sourceMapRegex.FindAllSubmatch(body, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	mapping := string(matches[len(matches)-1][1])
+	if strings.HasPrefix(mapping, "data:") {
+		return ""
+	}
+	return mapping
+}
+
+type sourceMap struct {
+	SourceRoot     string   `json:"sourceRoot"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent"`
+}
+
+// findSourceMapLinks extracts paths from the original file names in a source map, along with any urls in the
+// original sources themselves
+func findSourceMapLinks(currentURL string, body []byte) []Link {
+
+	base, err := url.Parse(currentURL)
+	if err != nil {
+		return nil
+	}
+
+	var mapping sourceMap
+	if err := json.Unmarshal(body, &mapping); err != nil {
+		return nil
+	}
+
+	extractor := &linkExtractor{}
+	for _, source := range mapping.Sources {
+		if source = sourceFilePath(mapping.SourceRoot, source); source != "" {
+			extractor.add(source, "sourcemap:source")
+		}
+	}
+	for _, content := range mapping.SourcesContent {
+		extractor.extractJS([]byte(content), "sourcemap")
+	}
+
+	return resolveLinks(base, extractor.links)
+}
+
+// sourceFilePath converts a file name from a source map to a path relative to the map, dropping bundler prefixes such
+// as webpack:/// and anything from node_modules
+func sourceFilePath(root string, source string) string {
+	if strings.Contains(source, "node_modules/") {
+		return ""
+	}
+	if index := strings.Index(source, ":///"); index >= 0 {
+		source = source[index+4:]
+	} else if index := strings.Index(source, "://"); index >= 0 && !strings.HasPrefix(source, "http") {
+		source = source[index+3:]
+	}
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return source
+	}
+	source = strings.TrimPrefix(source, "~/")
+	for strings.HasPrefix(source, "./") {
+		source = source[2:]
+	}
+	if root != "" && !strings.HasPrefix(source, "/") {
+		source = strings.TrimSuffix(root, "/") + "/" + source
+	}
+	if strings.Contains(source, "?") || strings.Contains(source, " ") {
+		return ""
+	}
+	return source
+}
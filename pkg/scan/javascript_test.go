@@ -0,0 +1,120 @@
+package scan
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func linkSources(links []Link) map[string]string {
+	sources := make(map[string]string)
+	for _, link := range links {
+		sources[link.URL] = link.Source
+	}
+	return sources
+}
+
+func TestFindJSLinks(t *testing.T) {
+
+	script := `
+const api = "/api/v2";
+fetch("/api/v1/users?active=true").then(r => r.json());
+axios.post('/api/v1/login', { user: user });
+xhr.open("GET", "/internal/status");
+$.ajax({ url: "/legacy/report.php", type: "POST" });
+const route = "/users/:id/profile";
+const template = ` + "`/orders/${orderId}`" + `;
+const absolute = "https://example.com/cdn/app.css";
+const external = "https://cdn.other.com/lib.js";
+const relative = "static/config.json";
+const mime = "application/json";
+const sentence = "this is not a path";
+const date = "12/31/2020";
+`
+
+	sources := linkSources(findJSLinks("https://example.com/assets/app.js", []byte(script)))
+
+	assert.Equal(t, map[string]string{
+		"https://example.com/api/v2":                    "js:string",
+		"https://example.com/api/v1/users?active=true":  "js:fetch",
+		"https://example.com/api/v1/login":              "js:axios",
+		"https://example.com/internal/status":           "js:xhr",
+		"https://example.com/legacy/report.php":         "js:ajax",
+		"https://example.com/users/":                    "js:string",
+		"https://example.com/orders/":                   "js:string",
+		"https://example.com/cdn/app.css":               "js:string",
+		"https://example.com/assets/static/config.json": "js:string",
+		"https://cdn.other.com/lib.js":                  "js:string",
+	}, sources)
+}
+
+func TestFindSourceMapLinks(t *testing.T) {
+
+	mapping := `{
+		"version": 3,
+		"sources": [
+			"webpack:///./src/components/AdminPanel.vue",
+			"webpack:///./node_modules/vue/dist/vue.js",
+			"../src/api/client.ts"
+		],
+		"sourcesContent": ["", "", "export const base = fetch('/api/hidden/endpoint');"]
+	}`
+
+	sources := linkSources(findSourceMapLinks("https://example.com/assets/app.js.map", []byte(mapping)))
+
+	assert.Equal(t, map[string]string{
+		"https://example.com/assets/src/components/AdminPanel.vue": "sourcemap:source",
+		"https://example.com/src/api/client.ts":                    "sourcemap:source",
+		"https://example.com/api/hidden/endpoint":                  "sourcemap:fetch",
+	}, sources)
+}
+
+func TestURLScannerSpidersJavaScript(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<script src="/app.js"></script>`))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			_, _ = w.Write([]byte("fetch('/api/secret');\n//# sourceMappingURL=app.js.map\n"))
+		case "/app.js.map":
+			_, _ = w.Write([]byte(`{"version":3,"sources":["webpack:///./admin/index.js"]}`))
+		case "/api/secret", "/admin/index.js":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader(nil))),
+		WithSpidering(true),
+		WithBackupExtensions(nil),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	var found []string
+	for _, result := range results {
+		found = append(found, result.Path)
+	}
+	sort.Strings(found)
+	assert.Equal(t, []string{"/", "/admin/index.js", "/api/secret", "/app.js", "/app.js.map"}, found)
+}
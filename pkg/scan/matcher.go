@@ -0,0 +1,248 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response is what matchers and filters are evaluated against
+type Response struct {
+	StatusCode int
+	Size       int
+	Header     http.Header
+	Body       []byte // up to maxBodySize bytes of the body
+	ResponseMetadata
+}
+
+// Matcher decides whether a response has some property
+type Matcher interface {
+	Match(response *Response) bool
+}
+
+// MatcherFunc adapts a function to the Matcher interface
+type MatcherFunc func(response *Response) bool
+
+func (f MatcherFunc) Match(response *Response) bool {
+	return f(response)
+}
+
+// Mode decides how several matchers or filters are combined
+type Mode int
+
+const (
+	ModeAnd Mode = iota // every one must match
+	ModeOr              // any one must match
+)
+
+// ParseMode parses "and" or "or"
+func ParseMode(input string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "and":
+		return ModeAnd, nil
+	case "or":
+		return ModeOr, nil
+	default:
+		return ModeAnd, fmt.Errorf("invalid mode '%s': must be 'and' or 'or'", input)
+	}
+}
+
+func (m Mode) String() string {
+	if m == ModeOr {
+		return "or"
+	}
+	return "and"
+}
+
+// Range is an inclusive range of values
+type Range struct {
+	Min int
+	Max int
+}
+
+// ParseRange parses a single value ("100"), a range ("100-200"), or a threshold (">100", "<100")
+func ParseRange(input string) (Range, error) {
+	input = strings.TrimSpace(input)
+	invalid := fmt.Errorf("invalid range '%s'", input)
+	switch {
+	case strings.HasPrefix(input, ">"):
+		value, err := strconv.Atoi(strings.TrimSpace(input[1:]))
+		if err != nil {
+			return Range{}, invalid
+		}
+		return Range{Min: value + 1, Max: math.MaxInt32}, nil
+	case strings.HasPrefix(input, "<"):
+		value, err := strconv.Atoi(strings.TrimSpace(input[1:]))
+		if err != nil {
+			return Range{}, invalid
+		}
+		return Range{Min: math.MinInt32, Max: value - 1}, nil
+	case strings.Contains(input, "-"):
+		parts := strings.SplitN(input, "-", 2)
+		min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return Range{}, invalid
+		}
+		max, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || max < min {
+			return Range{}, invalid
+		}
+		return Range{Min: min, Max: max}, nil
+	default:
+		value, err := strconv.Atoi(input)
+		if err != nil {
+			return Range{}, invalid
+		}
+		return Range{Min: value, Max: value}, nil
+	}
+}
+
+// ParseRanges parses each of the given inputs with ParseRange
+func ParseRanges(inputs []string) ([]Range, error) {
+	var ranges []Range
+	for _, input := range inputs {
+		r, err := ParseRange(input)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func (r Range) contains(value int) bool {
+	return value >= r.Min && value <= r.Max
+}
+
+func inRanges(ranges []Range, value int) bool {
+	for _, r := range ranges {
+		if r.contains(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusMatcher matches responses with any of the given status codes
+func StatusMatcher(codes ...int) Matcher {
+	return statusMatcher(codes)
+}
+
+type statusMatcher []int
+
+func (codes statusMatcher) Match(response *Response) bool {
+	return containsInt(codes, response.StatusCode)
+}
+
+// SizeMatcher matches responses whose body size is within any of the given ranges
+func SizeMatcher(ranges ...Range) Matcher {
+	return MatcherFunc(func(response *Response) bool {
+		return inRanges(ranges, response.Size)
+	})
+}
+
+// WordsMatcher matches responses whose word count is within any of the given ranges
+func WordsMatcher(ranges ...Range) Matcher {
+	return MatcherFunc(func(response *Response) bool {
+		return inRanges(ranges, response.Words)
+	})
+}
+
+// LinesMatcher matches responses whose line count is within any of the given ranges
+func LinesMatcher(ranges ...Range) Matcher {
+	return MatcherFunc(func(response *Response) bool {
+		return inRanges(ranges, response.Lines)
+	})
+}
+
+// TimeMatcher matches responses whose response time in milliseconds is within any of the given ranges
+func TimeMatcher(ranges ...Range) Matcher {
+	return MatcherFunc(func(response *Response) bool {
+		return inRanges(ranges, int(response.ResponseTime/time.Millisecond))
+	})
+}
+
+// RegexMatcher matches responses where the pattern is found in the headers or the body
+func RegexMatcher(pattern *regexp.Regexp) Matcher {
+	return MatcherFunc(func(response *Response) bool {
+		return pattern.Match(formatHeaders(response.Header)) || pattern.Match(response.Body)
+	})
+}
+
+// HeaderMatcher matches responses which have a header with the given name whose value matches the pattern
+func HeaderMatcher(name string, pattern *regexp.Regexp) Matcher {
+	return MatcherFunc(func(response *Response) bool {
+		for _, value := range response.Header.Values(name) {
+			if pattern.MatchString(value) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// formatHeaders writes headers in their wire format, sorted by name
+func formatHeaders(header http.Header) []byte {
+	var names []string
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buffer := bytes.NewBuffer(nil)
+	for _, name := range names {
+		for _, value := range header[name] {
+			buffer.WriteString(name + ": " + value + "\r\n")
+		}
+	}
+	return buffer.Bytes()
+}
+
+// responseRules decides whether a response is reported. It must satisfy the matchers, combined according to
+// matchMode, and must not satisfy the filters, combined according to filterMode. With no matchers every response
+// is matched, and with no filters none are filtered.
+type responseRules struct {
+	matchers   []Matcher
+	matchMode  Mode
+	filters    []Matcher
+	filterMode Mode
+}
+
+// matchedStatusCodes returns the codes which status matchers look for, which a scanner should treat as positive
+func (rules *responseRules) matchedStatusCodes() []int {
+	var codes []int
+	for _, matcher := range rules.matchers {
+		if status, ok := matcher.(statusMatcher); ok {
+			codes = append(codes, status...)
+		}
+	}
+	return codes
+}
+
+func (rules *responseRules) accept(response *Response) bool {
+	if len(rules.matchers) > 0 && !combine(rules.matchers, rules.matchMode, response) {
+		return false
+	}
+	if len(rules.filters) > 0 && combine(rules.filters, rules.filterMode, response) {
+		return false
+	}
+	return true
+}
+
+func combine(matchers []Matcher, mode Mode, response *Response) bool {
+	for _, matcher := range matchers {
+		matched := matcher.Match(response)
+		if mode == ModeOr && matched {
+			return true
+		}
+		if mode == ModeAnd && !matched {
+			return false
+		}
+	}
+	return mode == ModeAnd
+}
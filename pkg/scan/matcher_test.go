@@ -0,0 +1,110 @@
+package scan
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		input string
+		in    []int
+		out   []int
+	}{
+		{input: "100", in: []int{100}, out: []int{99, 101}},
+		{input: "100-200", in: []int{100, 150, 200}, out: []int{99, 201}},
+		{input: ">100", in: []int{101, 100000}, out: []int{100}},
+		{input: "<100", in: []int{0, 99}, out: []int{100}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			r, err := ParseRange(test.input)
+			require.NoError(t, err)
+			for _, value := range test.in {
+				assert.True(t, r.contains(value), "%d should be in range", value)
+			}
+			for _, value := range test.out {
+				assert.False(t, r.contains(value), "%d should not be in range", value)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "abc", "200-100", ">x", "1-"} {
+		_, err := ParseRange(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestResponseRules(t *testing.T) {
+
+	response := &Response{
+		StatusCode: 200,
+		Size:       150,
+		Header:     http.Header{"Server": []string{"nginx/1.18"}},
+		Body:       []byte("<h1>Welcome admin</h1>"),
+		ResponseMetadata: ResponseMetadata{
+			ResponseTime: 600 * time.Millisecond,
+			Words:        2,
+			Lines:        1,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		rules  responseRules
+		accept bool
+	}{
+		{name: "no rules", accept: true},
+		{
+			name:   "all matchers match",
+			rules:  responseRules{matchers: []Matcher{StatusMatcher(200), SizeMatcher(Range{100, 200})}},
+			accept: true,
+		},
+		{
+			name:   "one matcher fails in and mode",
+			rules:  responseRules{matchers: []Matcher{StatusMatcher(200), WordsMatcher(Range{5, 10})}},
+			accept: false,
+		},
+		{
+			name:   "one matcher matches in or mode",
+			rules:  responseRules{matchers: []Matcher{StatusMatcher(404), RegexMatcher(regexp.MustCompile("admin"))}, matchMode: ModeOr},
+			accept: true,
+		},
+		{
+			name:   "regex over headers",
+			rules:  responseRules{matchers: []Matcher{RegexMatcher(regexp.MustCompile("Server: nginx"))}},
+			accept: true,
+		},
+		{
+			name:   "header matcher",
+			rules:  responseRules{matchers: []Matcher{HeaderMatcher("server", regexp.MustCompile("^apache"))}},
+			accept: false,
+		},
+		{
+			name:   "filtered by time",
+			rules:  responseRules{filters: []Matcher{TimeMatcher(Range{Min: 501, Max: 100000})}},
+			accept: false,
+		},
+		{
+			name:   "not all filters match in and mode",
+			rules:  responseRules{filters: []Matcher{LinesMatcher(Range{1, 1}), StatusMatcher(404)}},
+			accept: true,
+		},
+		{
+			name:   "any filter matches in or mode",
+			rules:  responseRules{filters: []Matcher{LinesMatcher(Range{1, 1}), StatusMatcher(404)}, filterMode: ModeOr},
+			accept: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.accept, test.rules.accept(response))
+		})
+	}
+}
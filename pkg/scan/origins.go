@@ -0,0 +1,172 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Scheme decides whether vhosts are checked over HTTP, HTTPS or both
+type Scheme string
+
+const (
+	SchemeHTTP  Scheme = "http"
+	SchemeHTTPS Scheme = "https"
+	SchemeBoth  Scheme = "both" // check every port over both HTTP and HTTPS
+	SchemeAuto  Scheme = "auto" // use HTTPS on ports which complete a TLS handshake, and HTTP on the rest
+)
+
+// ParseScheme parses "http", "https", "both" or "auto"
+func ParseScheme(input string) (Scheme, error) {
+	scheme := Scheme(strings.ToLower(strings.TrimSpace(input)))
+	switch scheme {
+	case SchemeHTTP, SchemeHTTPS, SchemeBoth, SchemeAuto:
+		return scheme, nil
+	}
+	return SchemeHTTP, fmt.Errorf("invalid scheme '%s': must be 'http', 'https', 'both' or 'auto'", input)
+}
+
+// ParsePorts parses a comma separated list of ports and ranges, such as "80,443,8000-8100"
+func ParsePorts(input string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := parsePort(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = parsePort(bounds[1]); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid port range: %s", part)
+			}
+		}
+		for port := first; port <= last; port++ {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports, nil
+}
+
+func parsePort(input string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port: %s", input)
+	}
+	return port, nil
+}
+
+// endpoint is a port to check vhosts on, with the scheme to use - which is detected for each IP if auto
+type endpoint struct {
+	port   int
+	scheme Scheme
+}
+
+// endpoints returns the combinations of port and scheme to check vhosts on
+func (opt *VHOSTOptions) endpoints() []endpoint {
+
+	schemes := []Scheme{opt.Scheme}
+	switch opt.Scheme {
+	case "":
+		schemes = []Scheme{SchemeHTTP}
+		if opt.UseSSL {
+			schemes = []Scheme{SchemeHTTPS}
+		}
+	case SchemeBoth:
+		schemes = []Scheme{SchemeHTTP, SchemeHTTPS}
+	}
+
+	ports := opt.Ports
+	if opt.Port != 0 {
+		ports = append([]int{opt.Port}, ports...)
+	}
+
+	var endpoints []endpoint
+	seen := make(map[endpoint]bool)
+	add := func(port int, scheme Scheme) {
+		e := endpoint{port: port, scheme: scheme}
+		if !seen[e] {
+			seen[e] = true
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	if len(ports) == 0 {
+		// each scheme on its usual port, which could be either for auto
+		for _, scheme := range schemes {
+			switch scheme {
+			case SchemeHTTP:
+				add(80, scheme)
+			case SchemeHTTPS:
+				add(443, scheme)
+			default:
+				add(80, scheme)
+				add(443, scheme)
+			}
+		}
+		return endpoints
+	}
+
+	for _, port := range ports {
+		for _, scheme := range schemes {
+			add(port, scheme)
+		}
+	}
+	return endpoints
+}
+
+// probeTLS returns whether the server at address completes a TLS handshake
+func (scanner *VHOSTScanner) probeTLS(ctx context.Context, address string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, scanner.options.Timeout)
+	defer cancel()
+
+	conn, err := scanner.dial(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client := tls.Client(conn, &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         scanner.options.BaseDomain,
+	})
+	return client.HandshakeContext(ctx) == nil, nil
+}
+
+// dial connects to address, through the proxy if there is one
+func (scanner *VHOSTScanner) dial(ctx context.Context, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: scanner.options.Timeout,
+	}
+	if scanner.options.Proxy != nil {
+		return dialThroughProxy(ctx, dialer, scanner.options.Proxy, address)
+	}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// origin describes where a target sends requests, e.g. https://10.0.0.1:8443
+func origin(ip string, port int, ssl bool) string {
+	scheme := "http"
+	if ssl {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(ip, strconv.Itoa(port))
+}
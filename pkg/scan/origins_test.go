@@ -0,0 +1,41 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePorts(t *testing.T) {
+
+	ports, err := ParsePorts("80, 443,8000-8003,8001")
+	require.NoError(t, err)
+	assert.Equal(t, []int{80, 443, 8000, 8001, 8002, 8003}, ports)
+
+	for _, input := range []string{"http", "0", "65536", "90-80", "80-"} {
+		_, err := ParsePorts(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseScheme(t *testing.T) {
+
+	scheme, err := ParseScheme(" AUTO")
+	require.NoError(t, err)
+	assert.Equal(t, SchemeAuto, scheme)
+
+	_, err = ParseScheme("ftp")
+	assert.Error(t, err)
+}
+
+func TestVHOSTEndpoints(t *testing.T) {
+
+	assert.Equal(t, []endpoint{{80, SchemeHTTP}}, (&VHOSTOptions{}).endpoints())
+	assert.Equal(t, []endpoint{{443, SchemeHTTPS}}, (&VHOSTOptions{UseSSL: true}).endpoints())
+	assert.Equal(t, []endpoint{{8443, SchemeHTTPS}}, (&VHOSTOptions{UseSSL: true, Port: 8443}).endpoints())
+	assert.Equal(t, []endpoint{{80, SchemeHTTP}, {443, SchemeHTTPS}}, (&VHOSTOptions{Scheme: SchemeBoth}).endpoints())
+	assert.Equal(t, []endpoint{{80, SchemeAuto}, {443, SchemeAuto}}, (&VHOSTOptions{Scheme: SchemeAuto}).endpoints())
+	assert.Equal(t, []endpoint{{80, SchemeHTTP}, {80, SchemeHTTPS}, {8080, SchemeHTTP}, {8080, SchemeHTTPS}},
+		(&VHOSTOptions{Scheme: SchemeBoth, Port: 80, Ports: []int{8080, 80}}).endpoints())
+}
@@ -0,0 +1,187 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit controls how quickly requests are sent. Whatever the limits, a host which responds with 429 Too Many
+// Requests or 503 Service Unavailable is left alone for as long as its Retry-After header asks, or for an exponentially
+// increasing time if it has none, and the request is retried.
+type RateLimit struct {
+	RequestsPerSecond float64       // across every host - 0 is unlimited
+	PerHost           float64       // to any one host - 0 is unlimited
+	Jitter            time.Duration // add a random delay of up to this long before each request
+}
+
+// ThrottleEvent describes a host asking for requests to slow down
+type ThrottleEvent struct {
+	Host       string
+	StatusCode int
+	Wait       time.Duration // how long requests to the host are paused for
+}
+
+const (
+	minThrottleBackoff   = time.Second
+	maxThrottleBackoff   = time.Minute
+	maxRetryAfter        = time.Minute * 5 // longer waits are not worth honouring in full, so are cut short
+	maxThrottledAttempts = 5
+)
+
+var errThrottled = errors.New("throttled")
+
+// limiter spaces requests out according to a RateLimit, and pauses hosts which ask for it
+type limiter struct {
+	mutex  sync.Mutex
+	limit  RateLimit
+	next   time.Time // earliest time the next request may be sent to any host
+	hosts  map[string]*hostLimit
+	events chan<- ThrottleEvent // sent to without blocking, so events are dropped if nobody is listening
+}
+
+type hostLimit struct {
+	next    time.Time     // earliest time the next request may be sent to the host
+	backoff time.Duration // pause after the last throttling response without a Retry-After
+}
+
+func newLimiter(limit RateLimit, events chan<- ThrottleEvent) *limiter {
+	return &limiter{
+		limit:  limit,
+		hosts:  make(map[string]*hostLimit),
+		events: events,
+	}
+}
+
+func (l *limiter) host(host string) *hostLimit {
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostLimit{}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// wait blocks until a request may be sent to host, reserving its place so that concurrent requests are spaced out
+func (l *limiter) wait(ctx context.Context, host string) error {
+
+	l.mutex.Lock()
+	now := time.Now()
+	h := l.host(host)
+	at := now
+	if l.next.After(at) {
+		at = l.next
+	}
+	if h.next.After(at) {
+		at = h.next
+	}
+	if l.limit.RequestsPerSecond > 0 {
+		l.next = at.Add(time.Duration(float64(time.Second) / l.limit.RequestsPerSecond))
+	}
+	if l.limit.PerHost > 0 {
+		h.next = at.Add(time.Duration(float64(time.Second) / l.limit.PerHost))
+	}
+	l.mutex.Unlock()
+
+	delay := at.Sub(now)
+	if l.limit.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(l.limit.Jitter)))
+	}
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe checks the response to a request to host. If the host asked for requests to slow down, they are paused and
+// an error wrapping errThrottled is returned, so that the request can be retried.
+func (l *limiter) observe(host string, resp *http.Response) error {
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		l.mutex.Lock()
+		l.host(host).backoff = 0
+		l.mutex.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	wait := retryAfter(resp.Header.Get("Retry-After"), now)
+
+	l.mutex.Lock()
+	h := l.host(host)
+	if wait <= 0 {
+		h.backoff *= 2
+		if h.backoff < minThrottleBackoff {
+			h.backoff = minThrottleBackoff
+		}
+		if h.backoff > maxThrottleBackoff {
+			h.backoff = maxThrottleBackoff
+		}
+		wait = h.backoff
+	}
+	if until := now.Add(wait); until.After(h.next) {
+		h.next = until
+	}
+	l.mutex.Unlock()
+
+	if l.events != nil {
+		select {
+		case l.events <- ThrottleEvent{Host: host, StatusCode: resp.StatusCode, Wait: wait}:
+		default:
+		}
+	}
+
+	return fmt.Errorf("%w: %s responded with %d, waiting %s", errThrottled, host, resp.StatusCode, wait)
+}
+
+// do sends a request to host once the limits allow, sending it again if the host asks for requests to slow down. It is
+// for requests outside of the retry loops which scanners use for each job, so the request must not have a body.
+func (l *limiter) do(client *http.Client, req *http.Request, host string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := l.wait(req.Context(), host); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		err = l.observe(host, resp)
+		if err == nil {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+		if attempt == maxThrottledAttempts {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter parses a Retry-After header, which is either a number of seconds or a date
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		wait = date.Sub(now)
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait
+}
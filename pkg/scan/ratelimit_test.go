@@ -0,0 +1,115 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Duration(0), retryAfter("", now))
+	assert.Equal(t, time.Second*30, retryAfter("30", now))
+	assert.Equal(t, time.Minute*2, retryAfter("Wed, 01 Jan 2020 00:02:00 GMT", now))
+	assert.Equal(t, maxRetryAfter, retryAfter("86400", now))
+	assert.Equal(t, time.Duration(0), retryAfter("soon", now))
+}
+
+func TestLimiterSpacesRequests(t *testing.T) {
+
+	l := newLimiter(RateLimit{RequestsPerSecond: 50}, nil)
+
+	started := time.Now()
+	for i := 0; i < 6; i++ {
+		require.NoError(t, l.wait(context.Background(), "example.com"))
+	}
+	// the first request goes immediately, and each after it waits 20ms
+	assert.True(t, time.Since(started) >= time.Millisecond*100, "took %s", time.Since(started))
+}
+
+func TestLimiterPerHost(t *testing.T) {
+
+	l := newLimiter(RateLimit{PerHost: 10}, nil)
+
+	started := time.Now()
+	require.NoError(t, l.wait(context.Background(), "one.example.com"))
+	require.NoError(t, l.wait(context.Background(), "two.example.com"))
+	assert.True(t, time.Since(started) < time.Millisecond*50, "took %s", time.Since(started))
+
+	require.NoError(t, l.wait(context.Background(), "one.example.com"))
+	assert.True(t, time.Since(started) >= time.Millisecond*100, "took %s", time.Since(started))
+}
+
+func TestLimiterBacksOff(t *testing.T) {
+
+	events := make(chan ThrottleEvent, 4)
+	l := newLimiter(RateLimit{}, events)
+
+	throttled := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	assert.Error(t, l.observe("example.com", throttled))
+	assert.Error(t, l.observe("example.com", throttled))
+	assert.Equal(t, ThrottleEvent{Host: "example.com", StatusCode: http.StatusTooManyRequests, Wait: time.Second}, <-events)
+	assert.Equal(t, ThrottleEvent{Host: "example.com", StatusCode: http.StatusTooManyRequests, Wait: time.Second * 2}, <-events)
+
+	ok := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	assert.NoError(t, l.observe("example.com", ok))
+	assert.Error(t, l.observe("example.com", throttled))
+	assert.Equal(t, time.Second, (<-events).Wait)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.wait(ctx, "example.com"))
+	assert.NoError(t, l.wait(context.Background(), "other.example.com"))
+}
+
+func TestURLScannerHonoursRetryAfter(t *testing.T) {
+
+	var throttled int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login.php" && atomic.CompareAndSwapInt32(&throttled, 0, 1) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		switch r.URL.Path {
+		case "/login.php":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	events := make(chan ThrottleEvent, 1)
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(1),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("login")))),
+		WithExtensions([]string{"php"}),
+		WithBackupExtensions(nil),
+		WithThrottleChan(events),
+	)
+
+	started := time.Now()
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/login.php", results[0].String())
+	assert.True(t, time.Since(started) >= time.Second, "took %s", time.Since(started))
+	assert.Equal(t, ThrottleEvent{Host: parsed.Host, StatusCode: http.StatusTooManyRequests, Wait: time.Second}, <-events)
+}
@@ -0,0 +1,105 @@
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// RawRequest is a request template parsed from a raw HTTP/1.x request, such as one saved from Burp
+type RawRequest struct {
+	Method  string
+	URL     url.URL
+	Headers []string // "Name: value" pairs, in the order they appeared
+	Body    string
+}
+
+// ParseRawRequest parses a raw HTTP/1.x request. Raw requests rarely include a scheme, so the given one is used
+// unless the request line has an absolute url. If host is set, requests are sent to it instead of the Host header,
+// which is still sent as it was.
+func ParseRawRequest(r io.Reader, scheme string, host string) (*RawRequest, error) {
+
+	reader := bufio.NewReader(r)
+
+	line, err := readRawLine(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request line: %s", err)
+	}
+	parts := strings.Fields(line)
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "HTTP/") {
+		return nil, fmt.Errorf("invalid request line: %s", line)
+	}
+
+	target, err := url.Parse(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid request target: %s", err)
+	}
+
+	raw := &RawRequest{Method: parts[0]}
+
+	var hostHeader string
+	for {
+		line, err := readRawLine(reader)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		name := strings.SplitN(line, ":", 2)
+		if len(name) != 2 {
+			return nil, fmt.Errorf("invalid header: %s", line)
+		}
+		switch strings.ToLower(strings.TrimSpace(name[0])) {
+		case "host":
+			hostHeader = strings.TrimSpace(name[1])
+		case "content-length":
+			// recalculated for each request, as substituting words changes it
+		default:
+			raw.Headers = append(raw.Headers, line)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	raw.Body = string(body)
+
+	if target.Scheme != "" {
+		scheme = target.Scheme
+	}
+	if host == "" {
+		host = target.Host
+	}
+	if host == "" {
+		host = hostHeader
+	}
+	if host == "" {
+		return nil, fmt.Errorf("request has no Host header, so a host must be specified")
+	}
+	if hostHeader != "" && hostHeader != host {
+		raw.Headers = append([]string{"Host: " + hostHeader}, raw.Headers...)
+	}
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	target.Scheme = scheme
+	target.Host = host
+	raw.URL = *target
+
+	return raw, nil
+}
+
+// readRawLine reads a line without its line ending, which may be \r\n or \n
+func readRawLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
@@ -0,0 +1,105 @@
+package scan
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRawRequest(t *testing.T) {
+
+	raw := "POST /api/FUZZ?debug=1 HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Cookie: session=abc123\r\n" +
+		"Content-Type: application/json\r\n" +
+		"Content-Length: 17\r\n" +
+		"\r\n" +
+		`{"user":"admin"}` + "\n"
+
+	request, err := ParseRawRequest(strings.NewReader(raw), "https", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "POST", request.Method)
+	assert.Equal(t, "https://example.com/api/FUZZ?debug=1", request.URL.String())
+	assert.Equal(t, []string{"Cookie: session=abc123", "Content-Type: application/json"}, request.Headers)
+	assert.Equal(t, `{"user":"admin"}`+"\n", request.Body)
+}
+
+func TestParseRawRequestWithOverrides(t *testing.T) {
+
+	raw := "GET /index.php HTTP/1.1\nHost: intranet.local\n\n"
+
+	request, err := ParseRawRequest(strings.NewReader(raw), "http", "10.0.0.5:8080")
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://10.0.0.5:8080/index.php", request.URL.String())
+	assert.Equal(t, []string{"Host: intranet.local"}, request.Headers)
+	assert.Equal(t, "", request.Body)
+}
+
+func TestParseRawRequestErrors(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"GET /\r\n\r\n",
+		"GET / HTTP/1.1\r\nAccept: */*\r\n\r\n",
+		"GET / HTTP/1.1\r\nHost: example.com\r\nnot a header\r\n\r\n",
+	} {
+		_, err := ParseRawRequest(strings.NewReader(raw), "https", "")
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestURLScannerWithRawRequest(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPost && r.URL.Path == "/login" && r.Header.Get("Cookie") == "session=abc123" && string(body) == "user=admin&pass=letmein" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	raw := "POST /login HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Cookie: session=abc123\r\n" +
+		"Content-Length: 20\r\n" +
+		"\r\n" +
+		"user=admin&pass=FUZZ"
+
+	request, err := ParseRawRequest(strings.NewReader(raw), parsed.Scheme, parsed.Host)
+	require.NoError(t, err)
+
+	resultChan := make(chan URLResult, 1)
+	scanner := NewURLScanner(
+		WithRawRequest(request),
+		WithResultChan(resultChan),
+		WithPositiveStatusCodes([]int{http.StatusOK}),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("password\nletmein\nadmin")))),
+	)
+
+	_, err = scanner.Scan()
+	require.NoError(t, err)
+
+	var results []URLResult
+	for result := range resultChan {
+		results = append(results, result)
+	}
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, map[string]string{FuzzKeyword: "letmein"}, results[0].Values)
+}
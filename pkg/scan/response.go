@@ -0,0 +1,84 @@
+package scan
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// only this much of each response body is kept in memory for analysis - the rest is counted and discarded
+const maxBodySize = 10 * 1024 * 1024
+
+// ResponseMetadata describes the response which produced a result
+type ResponseMetadata struct {
+	ResponseTime time.Duration // time taken to receive the full response
+	ContentType  string
+	Location     string // redirect target, if any
+	Server       string
+	Words        int
+	Lines        int
+	Title        string // contents of the html <title> element, if any
+	BodyHash     string // md5 of the response body
+}
+
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// readBody reads a response body, returning up to maxBodySize bytes of it along with the full size
+func readBody(resp *http.Response) ([]byte, int, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, 0, err
+	}
+	size := len(body)
+	remaining, err := io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	size += int(remaining)
+	if size == 0 && resp.ContentLength > 0 {
+		// e.g. HEAD requests
+		size = int(resp.ContentLength)
+	}
+	return body, size, nil
+}
+
+func newResponseMetadata(resp *http.Response, body []byte, elapsed time.Duration) ResponseMetadata {
+	contentType := resp.Header.Get("Content-Type")
+	metadata := ResponseMetadata{
+		ResponseTime: elapsed,
+		ContentType:  contentType,
+		Location:     resp.Header.Get("Location"),
+		Server:       resp.Header.Get("Server"),
+		Words:        len(bytes.Fields(body)),
+		Lines:        countLines(body),
+		BodyHash:     md5Hash(string(body)),
+	}
+	if contentType == "" || strings.Contains(contentType, "html") {
+		metadata.Title = findTitle(body)
+	}
+	return metadata
+}
+
+func countLines(body []byte) int {
+	if len(body) == 0 {
+		return 0
+	}
+	lines := bytes.Count(body, []byte("\n"))
+	if body[len(body)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+func findTitle(body []byte) string {
+	matches := titleRegex.FindSubmatch(body)
+	if len(matches) < 2 {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(matches[1]))), " ")
+}
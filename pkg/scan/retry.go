@@ -0,0 +1,140 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryCondition is a kind of failure which a RetryPolicy can retry
+type RetryCondition int
+
+const (
+	RetryTimeout     RetryCondition = iota // the request timed out
+	RetryReset                             // the connection was reset or closed before a response was read
+	RetryServerError                       // the server responded with a 5xx status code
+	RetryThrottled                         // the server responded with 429, or 503 which is also treated as throttling
+)
+
+// ParseRetryCondition parses "timeout", "reset", "5xx" or "429"
+func ParseRetryCondition(input string) (RetryCondition, error) {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "timeout":
+		return RetryTimeout, nil
+	case "reset":
+		return RetryReset, nil
+	case "5xx":
+		return RetryServerError, nil
+	case "429":
+		return RetryThrottled, nil
+	default:
+		return RetryTimeout, fmt.Errorf("invalid retry condition '%s': must be 'timeout', 'reset', '5xx' or '429'", input)
+	}
+}
+
+func (c RetryCondition) String() string {
+	switch c {
+	case RetryReset:
+		return "reset"
+	case RetryServerError:
+		return "5xx"
+	case RetryThrottled:
+		return "429"
+	}
+	return "timeout"
+}
+
+// RetryPolicy decides which failed requests are sent again, and how long to wait before each retry. The wait starts at
+// BaseDelay and doubles with each retry up to MaxDelay, plus a random delay of up to Jitter. If the last attempt gets a
+// 5xx response, it is checked like any other.
+type RetryPolicy struct {
+	Attempts  int // including the first, so 1 never retries
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Jitter    time.Duration
+	Retry     []RetryCondition // which failures are retried
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:  5,
+	BaseDelay: time.Millisecond * 500,
+	MaxDelay:  time.Second * 10,
+	Jitter:    time.Millisecond * 250,
+	Retry:     []RetryCondition{RetryTimeout, RetryReset, RetryThrottled},
+}
+
+// errServerError is returned by an attempt which got a 5xx response that should be retried
+var errServerError = errors.New("server error")
+
+func (p RetryPolicy) has(condition RetryCondition) bool {
+	for _, c := range p.Retry {
+		if c == condition {
+			return true
+		}
+	}
+	return false
+}
+
+// retryStatus returns whether a response with the status code should be retried, unless it is from the last attempt
+func (p RetryPolicy) retryStatus(code int) bool {
+	return code >= 500 && code <= 599 && p.has(RetryServerError)
+}
+
+// retries returns whether the policy retries an attempt which failed with err
+func (p RetryPolicy) retries(err error) bool {
+	switch {
+	case errors.Is(err, errThrottled):
+		return p.has(RetryThrottled)
+	case errors.Is(err, errServerError):
+		return p.has(RetryServerError)
+	}
+	switch classifyError(err) {
+	case ErrorTimeout:
+		return p.has(RetryTimeout)
+	case ErrorReset:
+		return p.has(RetryReset)
+	}
+	return false
+}
+
+// delay returns how long to wait before the given retry, counting from 1
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	return delay
+}
+
+// do calls attempt until it succeeds, fails in a way the policy does not retry, or runs out of attempts. attempt is
+// told whether it is the last, so that it can accept a response it would otherwise retry. It returns how many times
+// the request was retried, and the error from the last attempt.
+func (p RetryPolicy) do(ctx context.Context, name string, attempt func(last bool) error) (int, error) {
+	for retries := 0; ; retries++ {
+		last := retries+1 >= p.Attempts
+		err := attempt(last)
+		if err == nil || last || ctx.Err() != nil || !p.retries(err) {
+			return retries, err
+		}
+		delay := p.delay(retries + 1)
+		logrus.Debugf("Retrying %s in %s (attempt %d of %d): %s", name, delay.Round(time.Millisecond), retries+2, p.Attempts, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
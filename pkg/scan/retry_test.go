@@ -0,0 +1,150 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryCondition(t *testing.T) {
+	for _, condition := range []RetryCondition{RetryTimeout, RetryReset, RetryServerError, RetryThrottled} {
+		parsed, err := ParseRetryCondition(condition.String())
+		require.NoError(t, err)
+		assert.Equal(t, condition, parsed)
+	}
+	_, err := ParseRetryCondition("dns")
+	assert.Error(t, err)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Millisecond * 100, MaxDelay: time.Second}
+	assert.Equal(t, time.Millisecond*100, policy.delay(1))
+	assert.Equal(t, time.Millisecond*200, policy.delay(2))
+	assert.Equal(t, time.Millisecond*400, policy.delay(3))
+	assert.Equal(t, time.Second, policy.delay(5))
+	assert.Equal(t, time.Second, policy.delay(100))
+
+	policy.Jitter = time.Millisecond * 50
+	delay := policy.delay(1)
+	assert.True(t, delay >= time.Millisecond*100 && delay < time.Millisecond*150, "delay was %s", delay)
+}
+
+func TestRetryPolicyRetries(t *testing.T) {
+	policy := RetryPolicy{Retry: []RetryCondition{RetryReset, RetryThrottled}}
+	assert.True(t, policy.retries(&url.Error{Op: "Get", URL: "http://example.com", Err: io.EOF}))
+	assert.True(t, policy.retries(fmt.Errorf("%w: example.com responded with 429", errThrottled)))
+	assert.False(t, policy.retries(fmt.Errorf("%w: example.com responded with 500", errServerError)))
+	assert.False(t, policy.retries(&url.Error{Op: "Get", URL: "http://example.com", Err: timeoutError{}}))
+	assert.False(t, policy.retries(context.Canceled))
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+
+	policy := RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, Retry: []RetryCondition{RetryReset}}
+
+	var lasts []bool
+	retries, err := policy.do(context.Background(), "example.com", func(last bool) error {
+		lasts = append(lasts, last)
+		return io.EOF
+	})
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 2, retries)
+	assert.Equal(t, []bool{false, false, true}, lasts)
+
+	attempts := 0
+	retries, err = policy.do(context.Background(), "example.com", func(last bool) error {
+		attempts++
+		return io.ErrClosedPipe
+	})
+	assert.Equal(t, io.ErrClosedPipe, err)
+	assert.Equal(t, 0, retries)
+	assert.Equal(t, 1, attempts)
+
+	// cancelling the scan stops the wait before a retry
+	ctx, cancel := context.WithCancel(context.Background())
+	policy.BaseDelay = time.Hour
+	retries, err = policy.do(ctx, "example.com", func(last bool) error {
+		time.AfterFunc(time.Millisecond*10, cancel)
+		return io.EOF
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, retries)
+}
+
+func TestURLScannerRetryPolicy(t *testing.T) {
+
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		count := requests[r.URL.Path]
+		mutex.Unlock()
+		switch r.URL.Path {
+		case "/reset.php":
+			if count == 1 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err == nil {
+					_ = conn.Close()
+				}
+				return
+			}
+		case "/flaky.php":
+			if count < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+		case "/broken.php":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		default:
+			http.NotFound(w, r)
+			return
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("reset\nflaky\nbroken\n")))),
+		WithExtensions([]string{"php"}),
+		WithParallelism(1),
+		WithSeeding(false),
+		WithRetryPolicy(RetryPolicy{
+			Attempts:  3,
+			BaseDelay: time.Millisecond,
+			Retry:     []RetryCondition{RetryReset, RetryServerError},
+		}),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	var found []string
+	for _, result := range results {
+		found = append(found, result.Path)
+	}
+	assert.ElementsMatch(t, []string{"/reset.php", "/flaky.php", "/broken.php"}, found)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, 2, requests["/reset.php"])
+	assert.Equal(t, 3, requests["/flaky.php"])
+	assert.Equal(t, 3, requests["/broken.php"])
+}
@@ -0,0 +1,60 @@
+package scan
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Scope decides which links found by spidering, seeding or following redirects are checked. The zero value only
+// allows links on the same host as the target, with no other limits.
+type Scope struct {
+	Include    []*regexp.Regexp // if any are set, only links whose path and query match one of them are checked
+	Exclude    []*regexp.Regexp // links whose path and query match any of these are never checked e.g. ^/logout
+	MaxDepth   int              // how many links to follow away from urls found by brute-forcing - 0 is unlimited
+	Subdomains bool             // also allow links to subdomains of the target host
+	MaxPerPath int              // how many links to check for any one path, whatever their query strings - 0 is unlimited
+}
+
+// allows returns true if a link to target, found depth links away from a brute-forced url, is in scope for a scan of
+// root. MaxPerPath is enforced separately, as it depends on what has already been checked.
+func (s *Scope) allows(root *url.URL, target *url.URL, depth int) bool {
+
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+
+	if !s.allowsHost(root, target) {
+		return false
+	}
+
+	if s.MaxDepth > 0 && depth > s.MaxDepth {
+		return false
+	}
+
+	uri := target.RequestURI()
+	for _, exclusion := range s.Exclude {
+		if exclusion.MatchString(uri) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, inclusion := range s.Include {
+		if inclusion.MatchString(uri) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) allowsHost(root *url.URL, target *url.URL) bool {
+	if strings.EqualFold(target.Host, root.Host) {
+		return true
+	}
+	if !s.Subdomains || target.Port() != root.Port() {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(target.Hostname()), "."+strings.ToLower(root.Hostname()))
+}
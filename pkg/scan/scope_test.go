@@ -0,0 +1,108 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeAllows(t *testing.T) {
+
+	root, err := url.Parse("https://example.com/")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		scope  Scope
+		target string
+		depth  int
+		want   bool
+	}{
+		{"same host", Scope{}, "https://example.com/admin", 1, true},
+		{"other scheme on the same host", Scope{}, "http://example.com/admin", 1, true},
+		{"other host", Scope{}, "https://other.com/admin", 1, false},
+		{"subdomain not allowed", Scope{}, "https://api.example.com/", 1, false},
+		{"subdomain allowed", Scope{Subdomains: true}, "https://api.example.com/", 1, true},
+		{"lookalike host", Scope{Subdomains: true}, "https://notexample.com/", 1, false},
+		{"subdomain on another port", Scope{Subdomains: true}, "https://api.example.com:8443/", 1, false},
+		{"not http", Scope{}, "ftp://example.com/", 1, false},
+		{"within depth", Scope{MaxDepth: 2}, "https://example.com/a", 2, true},
+		{"beyond depth", Scope{MaxDepth: 2}, "https://example.com/a", 3, false},
+		{"excluded", Scope{Exclude: []*regexp.Regexp{regexp.MustCompile(`^/logout`)}}, "https://example.com/logout?next=/", 1, false},
+		{"excluded by query", Scope{Exclude: []*regexp.Regexp{regexp.MustCompile(`action=delete`)}}, "https://example.com/item?action=delete", 1, false},
+		{"included", Scope{Include: []*regexp.Regexp{regexp.MustCompile(`^/api/`)}}, "https://example.com/api/users", 1, true},
+		{"not included", Scope{Include: []*regexp.Regexp{regexp.MustCompile(`^/api/`)}}, "https://example.com/calendar/", 1, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target, err := url.Parse(test.target)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, test.scope.allows(root, target, test.depth))
+		})
+	}
+}
+
+func TestURLScannerSpiderScope(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<a href="/logout">Logout</a><a href="/one">One</a><a href="/calendar?day=1">Calendar</a>`))
+		case "/one":
+			_, _ = w.Write([]byte(`<a href="/two">Two</a>`))
+		case "/two":
+			_, _ = w.Write([]byte(`<a href="/three">Three</a>`))
+		case "/calendar":
+			var day int
+			_, _ = fmt.Sscanf(r.URL.Query().Get("day"), "%d", &day)
+			_, _ = fmt.Fprintf(w, `<a href="/calendar?day=%d">Next</a>`, day+1)
+		case "/logout", "/three":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	results := make(chan URLResult, 32)
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader(nil))),
+		WithSpidering(true),
+		WithBackupExtensions(nil),
+		WithResultChan(results),
+		WithScope(Scope{
+			Exclude:    []*regexp.Regexp{regexp.MustCompile(`^/logout`)},
+			MaxDepth:   2,
+			MaxPerPath: 1,
+		}),
+	)
+
+	_, err = scanner.Scan()
+	require.NoError(t, err)
+
+	var found []string
+	for len(results) > 0 {
+		result := <-results
+		found = append(found, result.URL.RequestURI())
+	}
+	sort.Strings(found)
+
+	assert.Equal(t, []string{"/", "/calendar?day=1", "/one", "/two"}, found)
+}
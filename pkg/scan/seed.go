@@ -0,0 +1,214 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// how many sitemaps to fetch when following sitemap indexes, as they can be enormous
+const maxSitemaps = 50
+
+// seed fetches robots.txt, sitemap.xml and security.txt from the target host and returns jobs for every path they
+// reveal which is in scope, tagged with where they came from
+func (scanner *URLScanner) seed(ctx context.Context) []URLJob {
+
+	root := scanner.targetURL
+	root.Path = "/"
+	root.RawPath = ""
+	root.RawQuery = ""
+	root.Fragment = ""
+
+	var links []Link
+
+	robots, sitemaps := scanner.seedRobots(ctx, &root)
+	links = append(links, robots...)
+
+	sitemaps = append([]string{root.String() + "sitemap.xml"}, sitemaps...)
+	links = append(links, scanner.seedSitemaps(ctx, &root, sitemaps)...)
+
+	links = append(links, scanner.seedSecurityTxt(ctx, &root)...)
+
+	var jobs []URLJob
+	for _, link := range resolveLinks(&root, links) {
+		target, err := url.Parse(link.URL)
+		if err != nil || !scanner.scope.allows(&scanner.targetURL, target, 1) {
+			continue
+		}
+		jobs = append(jobs, URLJob{URL: link.URL, Source: link.Source, LinkDepth: 1})
+	}
+	logrus.Debugf("Seeded %d urls from robots.txt, sitemaps and security.txt", len(jobs))
+	return jobs
+}
+
+// fetchSeed fetches a file with a plain GET request, returning its body if it exists
+func (scanner *URLScanner) fetchSeed(ctx context.Context, uri string) ([]byte, bool) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, false
+	}
+	scanner.setHeaders(req, URLJob{URL: uri})
+
+	resp, err := scanner.limiter.do(scanner.client, req, req.URL.Host)
+	if err != nil {
+		logrus.Debugf("Failed to fetch %s: %s", uri, err)
+		return nil, false
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	body, _, err := readBody(resp)
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// seedRobots returns the paths listed in robots.txt, along with any sitemaps it points to
+func (scanner *URLScanner) seedRobots(ctx context.Context, root *url.URL) ([]Link, []string) {
+
+	body, ok := scanner.fetchSeed(ctx, root.String()+"robots.txt")
+	if !ok {
+		return nil, nil
+	}
+
+	var links []Link
+	var sitemaps []string
+
+	lines := bufio.NewScanner(bytes.NewReader(body))
+	for lines.Scan() {
+		line := lines.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		directive := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		switch directive {
+		case "allow", "disallow":
+			// wildcards can't be requested, so take the path up to the first of them
+			if index := strings.IndexAny(value, "*$"); index >= 0 {
+				value = value[:index]
+			}
+			if value != "" && value != "/" {
+				links = append(links, Link{URL: value, Source: "robots.txt:" + directive})
+			}
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		}
+	}
+
+	return links, sitemaps
+}
+
+type sitemapDocument struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// seedSitemaps returns the urls listed in the given sitemaps, following sitemap indexes
+func (scanner *URLScanner) seedSitemaps(ctx context.Context, root *url.URL, sitemaps []string) []Link {
+
+	var links []Link
+	fetched := make(map[string]struct{})
+
+	for len(sitemaps) > 0 && len(fetched) < maxSitemaps && ctx.Err() == nil {
+
+		uri := sitemaps[0]
+		sitemaps = sitemaps[1:]
+
+		target, err := root.Parse(uri)
+		if err != nil || target.Host != root.Host {
+			continue
+		}
+		uri = target.String()
+		if _, ok := fetched[uri]; ok {
+			continue
+		}
+		fetched[uri] = struct{}{}
+
+		body, ok := scanner.fetchSeed(ctx, uri)
+		if !ok {
+			continue
+		}
+
+		document, err := parseSitemap(body)
+		if err != nil {
+			logrus.Debugf("Failed to parse sitemap %s: %s", uri, err)
+			continue
+		}
+
+		source := "sitemap:" + path.Base(target.Path)
+		for _, entry := range document.URLs {
+			links = append(links, Link{URL: strings.TrimSpace(entry.Loc), Source: source})
+		}
+		for _, entry := range document.Sitemaps {
+			sitemaps = append(sitemaps, strings.TrimSpace(entry.Loc))
+		}
+	}
+
+	return links
+}
+
+// parseSitemap parses a sitemap or sitemap index, which may be gzipped
+func parseSitemap(body []byte) (*sitemapDocument, error) {
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		body, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var document sitemapDocument
+	if err := xml.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("invalid sitemap: %s", err)
+	}
+	return &document, nil
+}
+
+// seedSecurityTxt returns the urls listed in security.txt, such as its policy and acknowledgements pages
+func (scanner *URLScanner) seedSecurityTxt(ctx context.Context, root *url.URL) []Link {
+
+	body, ok := scanner.fetchSeed(ctx, root.String()+".well-known/security.txt")
+	if !ok {
+		return nil
+	}
+
+	var links []Link
+	lines := bufio.NewScanner(bytes.NewReader(body))
+	for lines.Scan() {
+		parts := strings.SplitN(lines.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+			links = append(links, Link{URL: value, Source: "security.txt:" + strings.ToLower(strings.TrimSpace(parts[0]))})
+		}
+	}
+	return links
+}
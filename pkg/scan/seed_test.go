@@ -0,0 +1,73 @@
+package scan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLScannerSeeding(t *testing.T) {
+
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, _ = writer.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>/blog/hello-world</loc></url>
+	<url><loc>https://elsewhere.example/ignored</loc></url>
+</urlset>`))
+	require.NoError(t, writer.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /admin/ # keep out\nDisallow: /private*.php\nAllow: /\nSitemap: /sitemap-index.xml\n"))
+		case "/sitemap-index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/sitemap-posts.xml.gz</loc></sitemap></sitemapindex>`))
+		case "/sitemap-posts.xml.gz":
+			_, _ = w.Write(gzipped.Bytes())
+		case "/.well-known/security.txt":
+			_, _ = w.Write([]byte("Contact: mailto:security@example.com\nPolicy: " + "http://" + r.Host + "/security-policy\n"))
+		case "/admin/", "/private", "/blog/hello-world", "/security-policy":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	results := make(chan URLResult, 16)
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader(nil))),
+		WithSeeding(true),
+		WithBackupExtensions(nil),
+		WithResultChan(results),
+	)
+
+	_, err = scanner.Scan()
+	require.NoError(t, err)
+
+	sources := make(map[string]string)
+	for result := range results {
+		sources[result.URL.Path] = result.Source
+	}
+
+	assert.Equal(t, map[string]string{
+		"/admin/":           "robots.txt:disallow",
+		"/private":          "robots.txt:disallow",
+		"/blog/hello-world": "sitemap:sitemap-posts.xml.gz",
+		"/security-policy":  "security.txt:policy",
+	}, sources)
+}
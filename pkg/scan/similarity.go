@@ -0,0 +1,61 @@
+package scan
+
+import (
+	"bytes"
+	"hash/fnv"
+	"math/bits"
+	"unicode"
+)
+
+// Similarity decides how alike two responses must be to be treated as the same page
+type Similarity struct {
+	Tolerance float64 // fraction by which the size and word count may differ from those already seen
+	Distance  int     // most bits, out of 64, by which the simhashes of the bodies may differ
+}
+
+var DefaultSimilarity = Similarity{
+	Tolerance: 0.1,
+	Distance:  6,
+}
+
+// simhash fingerprints a body so that bodies which differ in only a few words have fingerprints which differ in only a
+// few bits. Words containing digits are ignored, as timestamps, counters and tokens change from one request to the next.
+func simhash(body []byte) uint64 {
+	var weights [64]int
+	words := bytes.FieldsFunc(bytes.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if bytes.IndexFunc(word, unicode.IsDigit) >= 0 {
+			continue
+		}
+		hash := fnv.New64a()
+		_, _ = hash.Write(word)
+		sum := hash.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint
+}
+
+// distance returns how many bits two simhashes differ by
+func distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// within returns whether value lies in [low, high], widened by the tolerance
+func (s Similarity) within(value int, low int, high int) bool {
+	slack := int(float64(high) * s.Tolerance)
+	return value >= low-slack && value <= high+slack
+}
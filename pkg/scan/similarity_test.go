@@ -0,0 +1,38 @@
+package scan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimhashDistance(t *testing.T) {
+
+	page := func(token string) []byte {
+		return []byte(fmt.Sprintf(`<html><head><title>Welcome</title></head><body>
+<h1>Welcome to the default page</h1>
+<p>This server has not been configured for the requested site yet. Please contact the administrator.</p>
+<form><input type="hidden" name="csrf" value="%s"><input type="submit" value="Contact us"></form>
+<p>Generated at %s</p>
+</body></html>`, token, token))
+	}
+
+	same := distance(simhash(page("a1b2c3")), simhash(page("d4e5f6")))
+	assert.True(t, same <= DefaultSimilarity.Distance, "distance %d", same)
+
+	other := []byte(strings.Repeat("admin dashboard login username password remember me forgot ", 5))
+	different := distance(simhash(page("a1b2c3")), simhash(other))
+	assert.True(t, different > DefaultSimilarity.Distance, "distance %d", different)
+
+	assert.Equal(t, 0, distance(simhash(nil), simhash([]byte{})))
+}
+
+func TestSimilarityWithin(t *testing.T) {
+	similarity := Similarity{Tolerance: 0.1}
+	assert.True(t, similarity.within(105, 100, 100))
+	assert.True(t, similarity.within(95, 100, 120))
+	assert.False(t, similarity.within(140, 100, 120))
+	assert.False(t, similarity.within(80, 100, 120))
+}
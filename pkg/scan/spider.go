@@ -0,0 +1,175 @@
+package scan
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link is a url found in a response, along with where it was found
+type Link struct {
+	URL    string
+	Source string // where the link was found e.g. "html:a[href]", "html:meta[refresh]", "html:comment"
+}
+
+// attributes which contain a single url
+var linkAttributes = []string{"href", "src", "action", "formaction", "data", "codebase", "poster", "background", "cite", "longdesc", "manifest"}
+
+var (
+	cssURLRegex      = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")\s]+)['"]?\s*\)|@import\s+['"]([^'"]+)['"]`)
+	absoluteURLRegex = regexp.MustCompile(`https?://[^\s"'<>()]+`)
+	refreshRegex     = regexp.MustCompile(`(?i)url\s*=\s*['"]?([^'"\s]+)`)
+)
+
+// findLinks extracts every link from an html document
+func findLinks(currentURL string, body []byte) []Link {
+
+	base, err := url.Parse(currentURL)
+	if err != nil {
+		return nil
+	}
+
+	extractor := &linkExtractor{}
+	extractor.extract(body, "html:")
+
+	// <base href> changes how every relative link in the document is resolved, wherever they appear
+	if extractor.base != "" {
+		if relative, err := url.Parse(extractor.base); err == nil {
+			base = base.ResolveReference(relative)
+		}
+	}
+
+	return resolveLinks(base, extractor.links)
+}
+
+// resolveLinks resolves links against base, discarding any which are invalid, duplicated, or not http(s). Links on
+// other hosts are kept, as whether they are followed depends on the scope of the scan.
+func resolveLinks(base *url.URL, links []Link) []Link {
+	var results []Link
+	seen := make(map[string]struct{})
+	for _, link := range links {
+		u, err := url.Parse(strings.TrimSpace(link.URL))
+		if err != nil {
+			// one malformed link should not lose the rest of the page
+			continue
+		}
+		target := base.ResolveReference(u)
+		if target.Scheme != "http" && target.Scheme != "https" {
+			continue
+		}
+		target.Fragment = ""
+		if _, ok := seen[target.String()]; ok {
+			continue
+		}
+		seen[target.String()] = struct{}{}
+		results = append(results, Link{URL: target.String(), Source: link.Source})
+	}
+	return results
+}
+
+type linkExtractor struct {
+	base  string
+	links []Link
+}
+
+func (e *linkExtractor) add(link string, source string) {
+	if link = strings.TrimSpace(link); link != "" {
+		e.links = append(e.links, Link{URL: link, Source: source})
+	}
+}
+
+func (e *linkExtractor) extract(body []byte, prefix string) {
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+
+	var rawTag string
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// the end of the document - the tokenizer copes with malformed markup rather than failing
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			e.extractTag(token, prefix)
+			if token.Data == "script" || token.Data == "style" {
+				rawTag = token.Data
+			}
+		case html.EndTagToken:
+			rawTag = ""
+		case html.TextToken:
+			switch rawTag {
+			case "script":
+				e.extractJS(tokenizer.Text(), prefix+"script")
+			case "style":
+				e.extractCSS(string(tokenizer.Text()), prefix+"style")
+			}
+		case html.CommentToken:
+			comment := tokenizer.Token().Data
+			// commented out markup often points at old or hidden content
+			nested := &linkExtractor{}
+			nested.extract([]byte(comment), prefix+"comment:")
+			e.links = append(e.links, nested.links...)
+			for _, match := range absoluteURLRegex.FindAllString(comment, -1) {
+				e.add(match, prefix+"comment")
+			}
+		}
+	}
+}
+
+func (e *linkExtractor) extractTag(token html.Token, prefix string) {
+
+	var httpEquiv, content string
+
+	for _, attr := range token.Attr {
+		key := strings.ToLower(attr.Key)
+		source := prefix + token.Data + "[" + key + "]"
+		switch {
+		case token.Data == "base" && key == "href":
+			// handled separately, as it changes how the other links are resolved
+			if e.base == "" {
+				e.base = attr.Val
+			}
+		case key == "srcset" || key == "imagesrcset":
+			for _, candidate := range strings.Split(attr.Val, ",") {
+				if fields := strings.Fields(candidate); len(fields) > 0 {
+					e.add(fields[0], source)
+				}
+			}
+		case key == "style":
+			e.extractCSS(attr.Val, source)
+		case key == "http-equiv":
+			httpEquiv = strings.ToLower(attr.Val)
+		case key == "content":
+			content = attr.Val
+		default:
+			for _, name := range linkAttributes {
+				if key == name {
+					e.add(attr.Val, source)
+					break
+				}
+			}
+		}
+	}
+
+	if token.Data == "meta" && httpEquiv == "refresh" {
+		if match := refreshRegex.FindStringSubmatch(content); match != nil {
+			e.add(match[1], prefix+"meta[refresh]")
+		}
+	}
+}
+
+func (e *linkExtractor) extractCSS(css string, source string) {
+	for _, match := range cssURLRegex.FindAllStringSubmatch(css, -1) {
+		link := match[1]
+		if link == "" {
+			link = match[2]
+		}
+		if !strings.HasPrefix(strings.ToLower(link), "data:") {
+			e.add(link, source)
+		}
+	}
+}
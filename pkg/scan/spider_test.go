@@ -0,0 +1,56 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindLinks(t *testing.T) {
+
+	page := `<html>
+<head>
+	<base href="/app/">
+	<meta http-equiv="refresh" content="5; url=/moved">
+	<link rel="stylesheet" href="css/main.css">
+	<style>body { background: url('img/bg.png'); } @import "css/print.css";</style>
+</head>
+<body>
+	<a href="login.php">Login</a>
+	<a href="%zz">Broken</a>
+	<a href="https://other.example.com/external">External</a>
+	<a href="mailto:admin@example.com">Email</a>
+	<form action="/search"><button formaction="/advanced-search">Go</button></form>
+	<img src="logo.png" srcset="logo-2x.png 2x, /static/logo-3x.png 3x">
+	<div style="background-image: url(/img/header.jpg)"></div>
+	<!-- <a href="/old-admin/">Old admin</a> see also http://example.com/backup.zip -->
+	<script>fetch("/api/v1/users"); var page = './settings';</script>
+</body>
+</html>`
+
+	links := findLinks("http://example.com/index.html", []byte(page))
+
+	sources := make(map[string]string)
+	for _, link := range links {
+		sources[link.URL] = link.Source
+	}
+
+	assert.Equal(t, map[string]string{
+		"http://example.com/moved":              "html:meta[refresh]",
+		"http://example.com/app/css/main.css":   "html:link[href]",
+		"http://example.com/app/img/bg.png":     "html:style",
+		"http://example.com/app/css/print.css":  "html:style",
+		"http://example.com/app/login.php":      "html:a[href]",
+		"http://example.com/search":             "html:form[action]",
+		"http://example.com/advanced-search":    "html:button[formaction]",
+		"http://example.com/app/logo.png":       "html:img[src]",
+		"http://example.com/app/logo-2x.png":    "html:img[srcset]",
+		"http://example.com/static/logo-3x.png": "html:img[srcset]",
+		"https://other.example.com/external":    "html:a[href]",
+		"http://example.com/img/header.jpg":     "html:div[style]",
+		"http://example.com/old-admin/":         "html:comment:a[href]",
+		"http://example.com/backup.zip":         "html:comment",
+		"http://example.com/api/v1/users":       "html:script:fetch",
+		"http://example.com/app/settings":       "html:script:string",
+	}, sources)
+}
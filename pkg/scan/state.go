@@ -0,0 +1,133 @@
+package scan
+
+import (
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// URLState is a snapshot of the progress of a URL scan, which can be saved and later passed to WithState to resume the scan
+type URLState struct {
+	WordlistPosition   int64          // how many words had been read from the wordlist
+	Queue              []URLJob       // jobs which had been loaded or discovered but not yet completed
+	VisitedJournalSize int64          // how much of the visited journal had been written, if there is one
+	Directories        []URLDirectory // directories waiting to be brute-forced, the first of which may have been partly loaded
+	Recursed           []string       // directories which have been brute-forced or are waiting to be
+	Results            []URLResult    // results found so far
+}
+
+// State returns a snapshot of the progress of the scan. It is safe to call while the scan is running.
+func (scanner *URLScanner) State() *URLState {
+
+	scanner.stateMutex.Lock()
+	defer scanner.stateMutex.Unlock()
+
+	state := &URLState{
+		WordlistPosition: scanner.wordPosition,
+		Results:          append([]URLResult{}, scanner.results...),
+	}
+
+	var ids []uint64
+	for id := range scanner.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		state.Queue = append(state.Queue, scanner.pending[id])
+	}
+
+	// the requests already made are not copied into the state - only how many of them are in the journal, if any
+	scanner.checkMutex.Lock()
+	if scanner.journal != nil {
+		size, err := scanner.journal.checkpoint()
+		if err != nil {
+			logrus.Errorf("Failed to write visited journal, so some requests may be made again on resume: %s", err)
+		}
+		state.VisitedJournalSize = size
+	}
+	scanner.checkMutex.Unlock()
+
+	if scanner.currentDirectory != nil {
+		state.Directories = append(state.Directories, *scanner.currentDirectory)
+	}
+	state.Directories = append(state.Directories, scanner.directories...)
+
+	for dir := range scanner.recursed {
+		state.Recursed = append(state.Recursed, dir)
+	}
+	sort.Strings(state.Recursed)
+
+	return state
+}
+
+// replayJournal marks the requests in the visited journal as made, up to where it had been written when the state
+// being resumed was saved. Jobs which were still pending are left out, as they were in progress and must be checked
+// again. Without a state to resume, the journal is started again from empty.
+func (scanner *URLScanner) replayJournal(state *URLState) {
+	var size int64
+	pending := make(map[string]struct{})
+	if state != nil {
+		size = state.VisitedJournalSize
+		for _, job := range state.Queue {
+			pending[scanner.jobKey(job)] = struct{}{}
+		}
+	}
+	err := scanner.journal.replay(size, func(key string) {
+		if _, ok := pending[key]; !ok {
+			scanner.checked.Visit(key)
+		}
+	})
+	if err != nil {
+		logrus.Errorf("Failed to replay visited journal, so some requests may be made again: %s", err)
+	}
+}
+
+func (scanner *URLScanner) restore(state *URLState) {
+	scanner.wordPosition = state.WordlistPosition
+	// results are marked too, so that they are not found again if there is no journal of the requests already made
+	for _, result := range state.Results {
+		scanner.checked.Visit(scanner.jobKey(URLJob{URL: result.URL.String(), Values: result.Values}))
+	}
+	for _, dir := range state.Recursed {
+		scanner.recursed[dir] = struct{}{}
+	}
+	scanner.directories = append(scanner.directories, state.Directories...)
+	scanner.results = append(scanner.results, state.Results...)
+	scanner.resumedJobs = scanner.track(append([]URLJob{}, state.Queue...))
+}
+
+// VHOSTState is a snapshot of the progress of a VHOST scan, which can be saved and later set as VHOSTOptions.State to resume the scan
+type VHOSTState struct {
+	WordlistPosition int64         // how many words had been read from the wordlist
+	Queue            []string      // vhosts which had been loaded but not yet checked
+	Results          []VHOSTResult // results found so far
+}
+
+// State returns a snapshot of the progress of the scan. It is safe to call while the scan is running.
+func (scanner *VHOSTScanner) State() *VHOSTState {
+
+	scanner.stateMutex.Lock()
+	defer scanner.stateMutex.Unlock()
+
+	state := &VHOSTState{
+		WordlistPosition: scanner.wordPosition,
+		Results:          append([]VHOSTResult{}, scanner.results...),
+	}
+
+	var ids []uint64
+	for id := range scanner.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		state.Queue = append(state.Queue, scanner.pending[id])
+	}
+
+	return state
+}
+
+func (scanner *VHOSTScanner) restore(state *VHOSTState) {
+	scanner.wordPosition = state.WordlistPosition
+	scanner.results = append(scanner.results, state.Results...)
+	scanner.resumedJobs = scanner.track(state.Queue)
+}
@@ -0,0 +1,140 @@
+package scan
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/liamg/scout/pkg/wordlist"
+)
+
+// FuzzKeyword is replaced with each word from the wordlist wherever it appears in the target url, method, headers or
+// body. When several wordlists are combined with wordlist.ClusterBomb or wordlist.Pitchfork, each of their keywords is
+// replaced instead.
+const FuzzKeyword = "FUZZ"
+
+// keywords returns the keywords which are replaced with words from the wordlist
+func (scanner *URLScanner) keywords() []string {
+	if combination, ok := scanner.words.(wordlist.Combination); ok {
+		return combination.Keywords()
+	}
+	return []string{FuzzKeyword}
+}
+
+// values returns the value of each keyword for the word which was just read from the wordlist
+func (scanner *URLScanner) values(word string) map[string]string {
+	if combination, ok := scanner.words.(wordlist.Combination); ok {
+		return combination.Values()
+	}
+	return map[string]string{FuzzKeyword: word}
+}
+
+// templated returns true if a keyword appears anywhere in the request, in which case jobs are built by replacing the
+// keywords rather than by appending words to the target url
+func (scanner *URLScanner) templated() bool {
+	return containsKeyword(scanner.targetURL.String(), scanner.keywords()) || scanner.templatedRequest()
+}
+
+// templatedRequest returns true if a keyword appears in the method, headers or body, so that requests for the same
+// url can differ
+func (scanner *URLScanner) templatedRequest() bool {
+	keywords := scanner.keywords()
+	if containsKeyword(scanner.method, keywords) || containsKeyword(scanner.body, keywords) {
+		return true
+	}
+	for _, header := range scanner.extraHeaders {
+		if containsKeyword(header, keywords) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKeyword(input string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(input, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateJobs returns the jobs required to check a set of values by substituting them into the request. Extensions
+// are only tried when a keyword ends the url path, where they would make sense, and is not itself an extension.
+func (scanner *URLScanner) templateJobs(values map[string]string) []URLJob {
+	template := scanner.targetURL.String()
+
+	last := ""
+	if !strings.Contains(template, "?") {
+		for keyword := range values {
+			if strings.HasSuffix(template, keyword) && !strings.HasSuffix(template, "."+keyword) && len(keyword) > len(last) {
+				last = keyword
+			}
+		}
+	}
+
+	var jobs []URLJob
+	if last != "" {
+		// templated scans are never recursed into, so are treated as already at the deepest level
+		for _, variation := range scanner.variations(values[last], scanner.maxDepth) {
+			job := URLJob{BasicOnly: variation.BasicOnly, Values: make(map[string]string)}
+			for keyword, value := range values {
+				job.Values[keyword] = value
+			}
+			job.Values[last] = variation.URL
+			jobs = append(jobs, job)
+		}
+	} else {
+		jobs = []URLJob{{BasicOnly: true, Values: values}}
+	}
+
+	for i := range jobs {
+		jobs[i].URL = render(template, jobs[i])
+	}
+	return jobs
+}
+
+// render replaces each keyword with its value for the given job
+func render(template string, job URLJob) string {
+	if len(job.Values) == 0 {
+		return template
+	}
+	var keywords []string
+	for keyword := range job.Values {
+		keywords = append(keywords, keyword)
+	}
+	// longer keywords go first, so that e.g. USERNAME is not replaced as USER
+	sort.Slice(keywords, func(i, j int) bool {
+		if len(keywords[i]) != len(keywords[j]) {
+			return len(keywords[i]) > len(keywords[j])
+		}
+		return keywords[i] < keywords[j]
+	})
+	var replacements []string
+	for _, keyword := range keywords {
+		replacements = append(replacements, keyword, job.Values[keyword])
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+// jobKey identifies the request made for a job, so that it is only made once
+func (scanner *URLScanner) jobKey(job URLJob) string {
+	uri := scanner.clean(job.URL)
+	if scanner.templatedRequest() {
+		return uri + "\x00" + FormatValues(job.Values)
+	}
+	return uri
+}
+
+// FormatValues describes the value of each keyword, sorted by keyword e.g. "PASS=secret USER=admin"
+func FormatValues(values map[string]string) string {
+	var keywords []string
+	for keyword := range values {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	var pairs []string
+	for _, keyword := range keywords {
+		pairs = append(pairs, keyword+"="+values[keyword])
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,137 @@
+package scan
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/liamg/scout/pkg/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingTransport struct {
+	count int32
+	next  http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.count, 1)
+	return t.next.RoundTrip(req)
+}
+
+func TestScannersLeaveDefaultTransportAlone(t *testing.T) {
+
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	tlsConfig := defaultTransport.TLSClientConfig
+	dial := reflect.ValueOf(defaultTransport.DialContext).Pointer()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login.php" || r.Host == "admin.site.eg" {
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	urlScanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("login\n")))),
+		WithExtensions([]string{"php"}),
+		WithSkipSSLVerification(true),
+		WithSeeding(false),
+	)
+	urls, err := urlScanner.Scan()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(urls))
+
+	vhostScanner := NewVHOSTScanner(&VHOSTOptions{
+		BaseDomain: "site.eg",
+		IP:         parsed.Hostname(),
+		Port:       port,
+		UseSSL:     true,
+		Wordlist:   wordlist.FromReader(bytes.NewReader([]byte("admin\n"))),
+	})
+	vhosts, err := vhostScanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin.site.eg"}, vhosts)
+
+	assert.True(t, tlsConfig == defaultTransport.TLSClientConfig, "TLS config of the default transport was changed")
+	assert.Equal(t, dial, reflect.ValueOf(defaultTransport.DialContext).Pointer())
+
+	// certificates are still verified for everything else
+	_, err = http.Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestURLScannerWithHTTPClient(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login.php" {
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	transport := &countingTransport{next: http.DefaultTransport}
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("login\n")))),
+		WithExtensions([]string{"php"}),
+		WithSeeding(false),
+		WithHTTPClient(&http.Client{Transport: transport}),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, "/login.php", results[0].Path)
+	assert.True(t, atomic.LoadInt32(&transport.count) > 0)
+}
+
+func TestVHOSTScannerWithTransport(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "admin.site.eg" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	transport := &http.Transport{}
+	scanner := NewVHOSTScanner(&VHOSTOptions{
+		BaseDomain: "site.eg",
+		IP:         parsed.Hostname(),
+		Port:       port,
+		Wordlist:   wordlist.FromReader(bytes.NewReader([]byte("admin\n"))),
+		Transport:  transport,
+	})
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin.site.eg"}, results)
+
+	// the given transport was copied rather than changed to send requests to the IP
+	assert.Nil(t, transport.DialContext)
+}
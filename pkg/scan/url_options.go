@@ -1,7 +1,9 @@
 package scan
 
 import (
+	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -91,6 +93,100 @@ func WithSpidering(spider bool) URLOption {
 	}
 }
 
+// WithSeeding fetches robots.txt, sitemap.xml and security.txt before the scan starts, and checks every path they list
+func WithSeeding(seed bool) URLOption {
+	return func(s *URLScanner) {
+		s.enableSeeding = seed
+	}
+}
+
+// WithScope limits which links found by spidering, seeding or following redirects are checked
+func WithScope(scope Scope) URLOption {
+	return func(s *URLScanner) {
+		s.scope = scope
+	}
+}
+
+// WithVisitedSet sets how the scanner remembers which requests it has made. The default, NewMapVisitedSet, is exact but
+// grows with the scan. NewBloomVisitedSet and NewDiskVisitedSet bound the memory used by very large scans. Whichever is
+// used, it is only restored when resuming a scan with WithState if WithVisitedJournal is used too.
+func WithVisitedSet(set VisitedSet) URLOption {
+	return func(s *URLScanner) {
+		s.checked = set
+	}
+}
+
+// WithVisitedJournal appends each request the scanner makes to a journal, so that a scan resumed with WithState makes
+// none of them again. The journal must be the one the scan being resumed was using.
+func WithVisitedJournal(journal *VisitedJournal) URLOption {
+	return func(s *URLScanner) {
+		s.journal = journal
+	}
+}
+
+// WithRateLimit limits how quickly requests are sent
+func WithRateLimit(limit RateLimit) URLOption {
+	return func(s *URLScanner) {
+		s.rateLimit = limit
+	}
+}
+
+// WithThrottleChan reports each time a host asks for requests to slow down. Events are dropped rather than holding up
+// the scan if the channel is not ready, and it is never closed.
+func WithThrottleChan(c chan ThrottleEvent) URLOption {
+	return func(s *URLScanner) {
+		s.throttleChan = c
+	}
+}
+
+// WithAdaptiveConcurrency grows and shrinks how many requests are in flight according to how the server copes, starting
+// from the parallelism
+func WithAdaptiveConcurrency(adaptive AdaptiveConcurrency) URLOption {
+	return func(s *URLScanner) {
+		s.adaptive = adaptive
+	}
+}
+
+// WithErrorChan reports each request which fails without a response, once any retries are exhausted. Errors are dropped
+// rather than holding up the scan if the channel is not ready, and it is never closed.
+func WithErrorChan(c chan ScanError) URLOption {
+	return func(s *URLScanner) {
+		s.errorChan = c
+	}
+}
+
+// WithErrorBudget aborts the scan with ErrTargetUnreachable once this many requests in a row have failed without a
+// response. 0, the default, never aborts.
+func WithErrorBudget(budget int) URLOption {
+	return func(s *URLScanner) {
+		s.errorBudget = budget
+	}
+}
+
+// WithRetryPolicy decides which failed requests are sent again, and how long to wait in between. The default is
+// DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) URLOption {
+	return func(s *URLScanner) {
+		s.retryPolicy = policy
+	}
+}
+
+// WithHTTPClient sends requests with a copy of client, which does not follow redirects and uses the timeout if it
+// has none. WithProxy and WithSkipSSLVerification have no effect, as the client's transport is used.
+func WithHTTPClient(client *http.Client) URLOption {
+	return func(s *URLScanner) {
+		s.client = client
+	}
+}
+
+// WithTransport sends requests with transport instead of one the scanner builds for itself. WithProxy and
+// WithSkipSSLVerification have no effect.
+func WithTransport(transport http.RoundTripper) URLOption {
+	return func(s *URLScanner) {
+		s.transport = transport
+	}
+}
+
 func WithSkipSSLVerification(skipSSL bool) URLOption {
 	return func(s *URLScanner) {
 		s.skipSSLVerification = skipSSL
@@ -114,8 +210,72 @@ func WithMethod(method string) URLOption {
 	}
 }
 
+// WithBody sends the given body with every request. FuzzKeyword is replaced with each word.
+func WithBody(body string) URLOption {
+	return func(s *URLScanner) {
+		s.body = body
+	}
+}
+
+// WithRecursion enables brute-forcing of discovered directories, up to the given depth below the target url
+func WithRecursion(maxDepth int) URLOption {
+	return func(s *URLScanner) {
+		s.maxDepth = maxDepth
+	}
+}
+
+// WithRecursionExclusions prevents recursion into directories whose path matches any of the given expressions
+func WithRecursionExclusions(exclusions []*regexp.Regexp) URLOption {
+	return func(s *URLScanner) {
+		s.recursionExclusions = exclusions
+	}
+}
+
+// WithAutoCalibration requests random non-existent paths before scanning each directory, and ignores any results which look the same
+func WithAutoCalibration(calibrate bool) URLOption {
+	return func(s *URLScanner) {
+		s.autoCalibrate = calibrate
+	}
+}
+
+// WithRawRequest uses a raw request as the template for every request, replacing the target url, method, headers and body
+func WithRawRequest(raw *RawRequest) URLOption {
+	return func(s *URLScanner) {
+		s.targetURL = raw.URL
+		s.method = raw.Method
+		s.extraHeaders = append([]string{}, raw.Headers...)
+		s.body = raw.Body
+	}
+}
+
+// WithMatchers only reports responses which satisfy the given matchers - all of them with ModeAnd, or any of them with ModeOr
+func WithMatchers(mode Mode, matchers ...Matcher) URLOption {
+	return func(s *URLScanner) {
+		s.rules.matchMode = mode
+		s.rules.matchers = append(s.rules.matchers, matchers...)
+	}
+}
+
+// WithFilters hides responses which satisfy the given filters - all of them with ModeAnd, or any of them with ModeOr
+func WithFilters(mode Mode, filters ...Matcher) URLOption {
+	return func(s *URLScanner) {
+		s.rules.filterMode = mode
+		s.rules.filters = append(s.rules.filters, filters...)
+	}
+}
+
+// WithState resumes a previous scan from a snapshot taken with State. The other options should match those of the original scan.
+func WithState(state *URLState) URLOption {
+	return func(s *URLScanner) {
+		s.resumeState = state
+	}
+}
+
 type URLResult struct {
 	URL        url.URL
 	StatusCode int
 	Size       int
+	Values     map[string]string // value of each keyword, when they appear in the method, headers or body
+	Source     string            // how the url was discovered e.g. "redirect", "html:a[href]" - empty for words from the wordlist
+	ResponseMetadata
 }
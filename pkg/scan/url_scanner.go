@@ -2,35 +2,32 @@ package scan
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
+	"regexp"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/liamg/scout/internal/app/scout/data"
 
 	"github.com/liamg/scout/pkg/wordlist"
 
-	"github.com/avast/retry-go"
 	"github.com/sirupsen/logrus"
 )
 
 type URLScanner struct {
 	client              *http.Client
-	jobChan             chan URLJob
-	targetURL           url.URL        // target url
-	positiveStatusCodes []int          // status codes that indicate the existance of a file/directory
-	timeout             time.Duration  // http request timeout
-	parallelism         int            // parallel routines
-	resultChan          chan URLResult // chan to return results on - otherwise will be returned in slice
-	busyChan            chan string    // chan to use to update current job
+	transport           http.RoundTripper // used instead of the scanner's own transport, unless a client was given
+	targetURL           url.URL           // target url
+	positiveStatusCodes []int             // status codes that indicate the existance of a file/directory
+	timeout             time.Duration     // http request timeout
+	parallelism         int               // parallel routines
+	resultChan          chan URLResult    // chan to return results on - otherwise will be returned in slice
+	busyChan            chan string       // chan to use to update current job
 	words               wordlist.Wordlist
 	extensions          []string
 	includeNoExtension  bool
@@ -39,26 +36,70 @@ type URLScanner struct {
 	backupExtensions    []string
 	extraHeaders        []string
 	enableSpidering     bool
-	checked             map[string]struct{}
+	enableSeeding       bool       // seed the scan with paths from robots.txt, sitemaps and security.txt
+	checked             VisitedSet // requests which have already been made, guarded by checkMutex
 	checkMutex          sync.Mutex
-	queueChan           chan URLJob
-	jobsLoaded          int32
+	journal             *VisitedJournal // requests which have been made, saved for resuming the scan, guarded by checkMutex
+	scope               Scope           // which links found by spidering, seeding and redirects are checked
+	pathRequests        map[string]int  // how many links have been checked for each path, guarded by checkMutex
+	frontier            *frontier       // jobs waiting to be checked
+	rateLimit           RateLimit
+	throttleChan        chan ThrottleEvent // chan to report hosts asking for requests to slow down
+	limiter             *limiter
+	errorChan           chan ScanError // chan to report requests which failed without a response
+	errorBudget         int            // abort after this many requests in a row fail - 0 is unlimited
+	failures            *failures
+	retryPolicy         RetryPolicy
+	adaptive            AdaptiveConcurrency // grow and shrink how many requests are in flight, instead of keeping parallelism
+	concurrency         *concurrency        // nil unless adaptive
 	proxy               *url.URL
 	method              string
 	negativeLengths     []int
+	maxDepth            int              // how many directory levels to recurse into - 0 disables recursion
+	recursionExclusions []*regexp.Regexp // paths matching any of these are never recursed into
+	recursed            map[string]struct{}
+	directories         []URLDirectory
+	currentDirectory    *URLDirectory
+	cachedWords         []string          // words kept in memory so they can be replayed against discovered directories
+	stateMutex          sync.RWMutex      // held for writing while taking a snapshot of the scan state, and for reading while changing it
+	progressMutex       sync.Mutex        // guards pending, directories, recursed and results
+	pending             map[uint64]URLJob // jobs queued or in progress
+	lastJobID           uint64
+	wordPosition        int64 // how many words have been read from the wordlist and loaded as jobs
+	results             []URLResult
+	resumeState         *URLState
+	resumedJobs         []URLJob
+	autoCalibrate       bool
+	baselines           map[string][]Baseline // responses for non-existent paths, by directory
+	baselineMutex       sync.Mutex
+	rules               responseRules // matchers and filters which decide whether a response is reported
+	body                string
 }
 
 type URLJob struct {
 	URL       string
-	BasicOnly bool // don;t bother adding .BAK etc.
+	BasicOnly bool              // don;t bother adding .BAK etc.
+	Depth     int               // directory depth relative to the target url
+	Values    map[string]string // value of each keyword, when words are substituted into the request
+	Source    string            // how the job was discovered e.g. "redirect", "html:a[href]" - empty for words from the wordlist
+	LinkDepth int               // how many links were followed to discover the job - 0 for words from the wordlist
+	id        uint64
 }
 
-const MaxURLs = 1000
+// URLDirectory is a discovered directory which will be brute-forced with the whole wordlist
+type URLDirectory struct {
+	URL      string
+	Depth    int
+	Position int // how many words have already been loaded for this directory
+}
 
 func NewURLScanner(options ...URLOption) *URLScanner {
 
 	scanner := &URLScanner{
-		checked: make(map[string]struct{}),
+		pathRequests: make(map[string]int),
+		recursed:     make(map[string]struct{}),
+		pending:      make(map[uint64]URLJob),
+		baselines:    make(map[string][]Baseline),
 		positiveStatusCodes: []int{
 			http.StatusOK,
 			http.StatusBadRequest,
@@ -83,7 +124,28 @@ func NewURLScanner(options ...URLOption) *URLScanner {
 		option(scanner)
 	}
 
-	scanner.queueChan = make(chan URLJob, MaxURLs*scanner.parallelism)
+	if scanner.retryPolicy.Attempts <= 0 {
+		scanner.retryPolicy = DefaultRetryPolicy
+	}
+
+	if scanner.checked == nil {
+		scanner.checked = NewMapVisitedSet()
+	}
+
+	scanner.limiter = newLimiter(scanner.rateLimit, scanner.throttleChan)
+	scanner.failures = newFailures(scanner.errorBudget, scanner.errorChan)
+
+	if scanner.adaptive.enabled() {
+		scanner.concurrency = newConcurrency(scanner.adaptive, scanner.parallelism)
+	}
+
+	if scanner.journal != nil {
+		scanner.replayJournal(scanner.resumeState)
+	}
+
+	if scanner.resumeState != nil {
+		scanner.restore(scanner.resumeState)
+	}
 
 	if scanner.words == nil {
 		wordlistBytes, err := data.Asset("assets/wordlist.txt")
@@ -93,52 +155,71 @@ func NewURLScanner(options ...URLOption) *URLScanner {
 		scanner.words = wordlist.FromReader(bytes.NewReader(wordlistBytes))
 	}
 
-	scanner.client = &http.Client{
-		Timeout: scanner.timeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-
-	if scanner.proxy != nil {
-		scanner.client.Transport = &http.Transport{Proxy: http.ProxyURL(scanner.proxy)}
-	}
-
-	if scanner.skipSSLVerification {
-		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		scanner.client.Transport = http.DefaultTransport
+	transport := scanner.transport
+	if transport == nil {
+		own := newTransport(scanner.workers(), scanner.skipSSLVerification)
+		if scanner.proxy != nil {
+			own.Proxy = http.ProxyURL(scanner.proxy)
+		}
+		transport = own
 	}
+	scanner.client = newClient(scanner.client, transport, scanner.timeout)
 
 	return scanner
 }
 
 func (scanner *URLScanner) Scan() ([]url.URL, error) {
+	return scanner.ScanContext(context.Background())
+}
+
+// ScanContext runs the scan until it completes or the context is cancelled. When cancelled, the results found so far
+// are returned along with the context error.
+func (scanner *URLScanner) ScanContext(ctx context.Context) ([]url.URL, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	atomic.StoreInt32(&(scanner.jobsLoaded), 0)
+	scanner.failures.start(cancel)
 
-	scanner.jobChan = make(chan URLJob, scanner.parallelism)
-	results := make(chan URLResult, scanner.parallelism)
+	scanner.frontier = newFrontier()
+	go func() {
+		<-ctx.Done()
+		scanner.frontier.stop()
+	}()
+
+	workers := scanner.workers()
+	results := make(chan URLResult, workers)
 
 	wg := sync.WaitGroup{}
 
 	logrus.Debug("Starting workers...")
 
-	for i := 0; i < scanner.parallelism; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
-			scanner.worker(results)
+			scanner.worker(ctx, results)
 			wg.Done()
 		}()
 	}
 
-	logrus.Debugf("Started %d workers!", scanner.parallelism)
+	logrus.Debugf("Started %d workers!", workers)
 
 	logrus.Debug("Starting results gatherer...")
 
 	waitChan := make(chan struct{})
 	var foundURLs []url.URL
 
+	scanner.progressMutex.Lock()
+	restored := append([]URLResult{}, scanner.results...)
+	scanner.progressMutex.Unlock()
+
 	go func() {
+		for _, result := range restored {
+			if scanner.resultChan != nil {
+				scanner.resultChan <- result
+			}
+			foundURLs = append(foundURLs, result.URL)
+		}
 		for result := range results {
 			if scanner.resultChan != nil {
 				scanner.resultChan <- result
@@ -153,47 +234,16 @@ func (scanner *URLScanner) Scan() ([]url.URL, error) {
 
 	logrus.Debug("Adding jobs...")
 
-	// add urls to scan...
-	prefix := scanner.targetURL.String()
-	if !strings.HasSuffix(prefix, "/") {
-		prefix = prefix + "/"
-	}
-
-	scanner.jobChan <- URLJob{URL: prefix}
-
-	for {
-		if word, err := scanner.words.Next(); err != nil {
-			if err != io.EOF {
-				return nil, err
-			}
-			break
-		} else {
-			if word == "" {
-				continue
-			}
-			uri := prefix + word
-
-			if scanner.filename != "" {
-				scanner.jobChan <- URLJob{URL: uri + "/" + scanner.filename, BasicOnly: true}
-			} else {
-				if scanner.includeNoExtension {
-					scanner.jobChan <- URLJob{URL: uri, BasicOnly: true}
-				}
-				if !strings.HasSuffix(uri, ".htaccess") && !strings.HasSuffix(uri, ".htpasswd") {
-					for _, ext := range scanner.extensions {
-						scanner.jobChan <- URLJob{URL: uri + "." + ext}
-					}
-				}
-			}
-		}
+	loadErr := scanner.loadJobs(ctx)
+	if loadErr != nil {
+		cancel()
 	}
 
-	atomic.StoreInt32(&(scanner.jobsLoaded), 1)
+	scanner.frontier.close()
 
 	logrus.Debug("Waiting for workers to complete...")
 
 	wg.Wait()
-	close(scanner.jobChan)
 	close(results)
 
 	logrus.Debug("Waiting for results...")
@@ -204,51 +254,369 @@ func (scanner *URLScanner) Scan() ([]url.URL, error) {
 		close(scanner.busyChan)
 	}
 
+	if loadErr != nil {
+		return foundURLs, loadErr
+	}
+
+	if err := scanner.failures.err(); err != nil {
+		return foundURLs, err
+	}
+
 	logrus.Debug("Complete!")
 
-	return foundURLs, nil
+	return foundURLs, ctx.Err()
 }
 
-func (scanner *URLScanner) worker(results chan<- URLResult) {
+// loadJobs adds jobs for every word in the wordlist, followed by jobs for any directories discovered along the way
+func (scanner *URLScanner) loadJobs(ctx context.Context) error {
 
-	for {
-		select {
-		case job := <-scanner.jobChan:
-			if result := scanner.checkURL(job); result != nil {
-				results <- *result
+	// add urls to scan...
+	prefix := scanner.prefix()
+
+	if scanner.autoCalibrate {
+		if _, err := scanner.calibrate(ctx, prefix); err != nil {
+			return err
+		}
+	}
+
+	// jobs which were pending when a previous scan was stopped go first
+	scanner.dispatch(ctx, scanner.resumedJobs)
+	scanner.resumedJobs = nil
+
+	templated := scanner.templated()
+	maxDepth := scanner.maxDepth
+	if templated {
+		// words are substituted into the request rather than appended to directories, so there is nothing to recurse into
+		maxDepth = 0
+	} else {
+		scanner.stateMutex.RLock()
+		scanner.progressMutex.Lock()
+		scanner.recursed[prefix] = struct{}{}
+		scanner.progressMutex.Unlock()
+		root := scanner.track([]URLJob{{URL: prefix}})
+		scanner.stateMutex.RUnlock()
+		scanner.dispatch(ctx, root)
+
+		if scanner.enableSeeding {
+			seeded := scanner.seed(ctx)
+			scanner.stateMutex.RLock()
+			seeded = scanner.track(seeded)
+			scanner.stateMutex.RUnlock()
+			scanner.dispatch(ctx, seeded)
+		}
+	}
+
+	var read int64
+	if scanner.wordPosition > 0 && maxDepth == 0 {
+		// when recursing, every word is needed again for each directory, so the skipped words are read and cached below instead
+		if seekable, ok := scanner.words.(wordlist.Seekable); ok {
+			if err := seekable.SeekTo(scanner.wordPosition); err != nil {
+				return err
 			}
-		EXTRA:
-			for {
-				select {
-				case extra := <-scanner.queueChan:
-					if result := scanner.checkURL(extra); result != nil {
-						results <- *result
-					}
-				default:
-					break EXTRA
+			read = scanner.wordPosition
+		}
+	}
+
+	for ctx.Err() == nil {
+		word, err := scanner.words.Next()
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		read++
+		if word != "" && maxDepth > 0 {
+			scanner.cachedWords = append(scanner.cachedWords, word)
+		}
+		if read <= scanner.wordPosition {
+			// already loaded before the scan was resumed
+			continue
+		}
+		var jobs []URLJob
+		if word != "" {
+			if templated {
+				jobs = scanner.templateJobs(scanner.values(word))
+			} else {
+				jobs = scanner.wordJobs(prefix, word, 0)
+			}
+		}
+		scanner.stateMutex.RLock()
+		jobs = scanner.track(jobs)
+		scanner.wordPosition = read
+		scanner.stateMutex.RUnlock()
+		scanner.dispatch(ctx, jobs)
+	}
+
+	if maxDepth > 0 {
+		logrus.Debug("Recursing into discovered directories...")
+		for ctx.Err() == nil {
+			dir, ok := scanner.nextDirectory()
+			if !ok {
+				// directories are always added before a job completes, so check once more when everything is idle
+				if !scanner.frontier.waitIdle() {
+					break
 				}
+				if dir, ok = scanner.nextDirectory(); !ok {
+					break
+				}
+			}
+			logrus.Debugf("Recursing into %s (depth %d)", dir.URL, dir.Depth)
+			if scanner.autoCalibrate {
+				if _, err := scanner.calibrate(ctx, dir.URL); err != nil {
+					logrus.Debugf("Failed to calibrate %s: %s", dir.URL, err)
+				}
+			}
+			for i := dir.Position; i < len(scanner.cachedWords) && ctx.Err() == nil; i++ {
+				jobs := scanner.wordJobs(dir.URL, scanner.cachedWords[i], dir.Depth)
+				scanner.stateMutex.RLock()
+				jobs = scanner.track(jobs)
+				scanner.currentDirectory.Position = i + 1
+				scanner.stateMutex.RUnlock()
+				scanner.dispatch(ctx, jobs)
 			}
-		default:
-			if atomic.LoadInt32(&scanner.jobsLoaded) > 0 {
-				return
+			if ctx.Err() == nil {
+				scanner.stateMutex.RLock()
+				scanner.currentDirectory = nil
+				scanner.stateMutex.RUnlock()
 			}
+		}
+	}
+
+	return nil
+}
 
-			time.Sleep(time.Millisecond * 100)
+// prefix returns the target url as a directory, ready for words to be appended
+func (scanner *URLScanner) prefix() string {
+	prefix := scanner.targetURL.String()
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+	return prefix
+}
+
+// wordJobs returns the jobs required to check for a word within the directory described by prefix
+func (scanner *URLScanner) wordJobs(prefix string, word string, depth int) []URLJob {
+	jobs := scanner.variations(word, depth)
+	for i := range jobs {
+		jobs[i].URL = prefix + jobs[i].URL
+		jobs[i].Depth = depth
+	}
+	return jobs
+}
+
+// variations returns jobs for each path which should be tried for a word, relative to the directory being searched at
+// the given depth
+func (scanner *URLScanner) variations(word string, depth int) []URLJob {
+
+	if scanner.filename != "" {
+		return []URLJob{{URL: word + "/" + scanner.filename, BasicOnly: true}}
+	}
+
+	var jobs []URLJob
+	if scanner.includeNoExtension {
+		jobs = append(jobs, URLJob{URL: word, BasicOnly: true})
+	} else if depth < scanner.maxDepth {
+		// with only extensions tried, no directory would ever be found to recurse into
+		jobs = append(jobs, URLJob{URL: word + "/", BasicOnly: true})
+	}
+	if !strings.HasSuffix(word, ".htaccess") && !strings.HasSuffix(word, ".htpasswd") {
+		for _, ext := range scanner.extensions {
+			jobs = append(jobs, URLJob{URL: word + "." + ext})
 		}
 	}
+	return jobs
 }
 
-func (scanner *URLScanner) queue(job URLJob) {
-	scanner.queueChan <- job
+func (scanner *URLScanner) worker(ctx context.Context, results chan<- URLResult) {
+	for {
+		job, ok := scanner.frontier.next()
+		if !ok {
+			return
+		}
+		scanner.process(ctx, job, results)
+		scanner.frontier.finish()
+	}
+}
+
+// process checks a job and records everything it led to in a single step, so that State never sees a partial outcome
+func (scanner *URLScanner) process(ctx context.Context, job URLJob, results chan<- URLResult) {
+
+	outcome := scanner.checkURL(ctx, job)
+	if ctx.Err() != nil {
+		// leave the job pending, so it is checked again if the scan is resumed
+		return
+	}
+
+	scanner.stateMutex.RLock()
+	discovered := scanner.track(outcome.jobs)
+	scanner.progressMutex.Lock()
+	if dir := outcome.directory; dir != nil {
+		if _, ok := scanner.recursed[dir.URL]; !ok {
+			scanner.recursed[dir.URL] = struct{}{}
+			scanner.directories = append(scanner.directories, *dir)
+		}
+	}
+	if outcome.result != nil {
+		scanner.results = append(scanner.results, *outcome.result)
+	}
+	delete(scanner.pending, job.id)
+	scanner.progressMutex.Unlock()
+	scanner.stateMutex.RUnlock()
+
+	scanner.frontier.push(discovered)
+
+	if outcome.result != nil {
+		results <- *outcome.result
+	}
+}
+
+// track assigns ids to new jobs and records them as pending until they have been processed. The state lock must be held.
+func (scanner *URLScanner) track(jobs []URLJob) []URLJob {
+	scanner.progressMutex.Lock()
+	defer scanner.progressMutex.Unlock()
+	for i := range jobs {
+		scanner.lastJobID++
+		jobs[i].id = scanner.lastJobID
+		scanner.pending[jobs[i].id] = jobs[i]
+	}
+	return jobs
+}
+
+// dispatch adds jobs from the loader to the frontier, waiting while there are already enough for every worker
+func (scanner *URLScanner) dispatch(ctx context.Context, jobs []URLJob) {
+	if ctx.Err() == nil {
+		scanner.frontier.pushWait(jobs, scanner.workers())
+	}
+}
+
+// workers returns how many worker routines to run - with adaptive concurrency, enough for the highest level allowed
+func (scanner *URLScanner) workers() int {
+	if scanner.concurrency != nil {
+		return scanner.concurrency.max
+	}
+	return scanner.parallelism
+}
+
+// Errors returns how many requests have failed without a response so far, by category
+func (scanner *URLScanner) Errors() map[ErrorCategory]int {
+	return scanner.failures.snapshot()
+}
+
+// Concurrency returns how many requests may currently be in flight
+func (scanner *URLScanner) Concurrency() int {
+	if scanner.concurrency != nil {
+		return scanner.concurrency.level()
+	}
+	return scanner.parallelism
+}
+
+// nextDirectory removes the next directory from the list of those waiting to be brute-forced and makes it current
+func (scanner *URLScanner) nextDirectory() (URLDirectory, bool) {
+	scanner.stateMutex.RLock()
+	defer scanner.stateMutex.RUnlock()
+	scanner.progressMutex.Lock()
+	defer scanner.progressMutex.Unlock()
+	if len(scanner.directories) == 0 {
+		return URLDirectory{}, false
+	}
+	dir := scanner.directories[0]
+	scanner.directories = scanner.directories[1:]
+	scanner.currentDirectory = &dir
+	return dir, true
+}
+
+// recurse returns the directory described by a result if it looks like one which should be brute-forced
+func (scanner *URLScanner) recurse(job URLJob, target *url.URL, code int, location string) *URLDirectory {
+
+	if job.Depth >= scanner.maxDepth {
+		return nil
+	}
+
+	dir := *target
+	dir.RawQuery = ""
+	dir.Fragment = ""
+
+	if !strings.HasSuffix(dir.Path, "/") {
+		// e.g. /admin redirecting to /admin/
+		if code < 300 || code >= 400 || location == "" {
+			return nil
+		}
+		relative, err := url.Parse(location)
+		if err != nil {
+			return nil
+		}
+		redirect := target.ResolveReference(relative)
+		if redirect.Host != target.Host || redirect.Path != target.Path+"/" {
+			return nil
+		}
+		dir.Path = redirect.Path
+	}
+
+	for _, exclusion := range scanner.recursionExclusions {
+		if exclusion.MatchString(dir.Path) {
+			return nil
+		}
+	}
+
+	return &URLDirectory{
+		URL:   dir.String(),
+		Depth: job.Depth + 1,
+	}
 }
 
 func (scanner *URLScanner) visited(uri string) bool {
 	scanner.checkMutex.Lock()
 	defer scanner.checkMutex.Unlock()
-	if _, ok := scanner.checked[uri]; ok {
+	if scanner.checked.Visit(uri) {
 		return true
 	}
-	scanner.checked[uri] = struct{}{}
+	if scanner.journal != nil {
+		scanner.journal.append(uri)
+	}
+	return false
+}
+
+// follow returns a job for a link found while checking job, if the link is in scope
+func (scanner *URLScanner) follow(job URLJob, target *url.URL, source string) (URLJob, bool) {
+	root := &scanner.targetURL
+	if scanner.templated() {
+		// the target may have a keyword in its host, so links are compared with the url which was actually requested
+		parsed, err := url.Parse(job.URL)
+		if err != nil {
+			return URLJob{}, false
+		}
+		root = parsed
+	}
+	if !scanner.scope.allows(root, target, job.LinkDepth+1) {
+		return URLJob{}, false
+	}
+	return URLJob{
+		URL:       target.String(),
+		Depth:     job.Depth,
+		Values:    job.Values,
+		Source:    source,
+		LinkDepth: job.LinkDepth + 1,
+	}, true
+}
+
+// pathCapped returns true if as many links to the path of uri have been checked as the scope allows, and otherwise
+// counts this one
+func (scanner *URLScanner) pathCapped(uri string) bool {
+	if scanner.scope.MaxPerPath <= 0 {
+		return false
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	key := parsed.Host + parsed.Path
+	scanner.checkMutex.Lock()
+	defer scanner.checkMutex.Unlock()
+	if scanner.pathRequests[key] >= scanner.scope.MaxPerPath {
+		return true
+	}
+	scanner.pathRequests[key]++
 	return false
 }
 
@@ -259,13 +627,62 @@ func (scanner *URLScanner) clean(url string) string {
 	return url
 }
 
+// newRequest creates a request for the given job, including any extra headers and body
+func (scanner *URLScanner) newRequest(ctx context.Context, job URLJob) (*http.Request, error) {
+	var body io.Reader
+	if scanner.body != "" {
+		body = strings.NewReader(render(scanner.body, job))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, render(scanner.method, job), job.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	scanner.setHeaders(req, job)
+
+	return req, nil
+}
+
+// setHeaders adds the extra headers to a request, with any keywords replaced for the given job
+func (scanner *URLScanner) setHeaders(req *http.Request, job URLJob) {
+	for _, header := range scanner.extraHeaders {
+		parts := strings.SplitN(render(header, job), ":", 2)
+		if len(parts) == 2 {
+			if strings.ToLower(parts[0]) == "host" {
+				req.Host = strings.TrimSpace(parts[1])
+			}
+			req.Header.Set(parts[0], strings.TrimPrefix(parts[1], " "))
+		}
+	}
+}
+
+// urlOutcome is everything that resulted from checking a job
+type urlOutcome struct {
+	result    *URLResult
+	jobs      []URLJob      // further jobs discovered, e.g. from redirects or links
+	directory *URLDirectory // directory to brute-force, if recursion is enabled
+}
+
 // hit a url - is it one of certain response codes? leave connections open!
-func (scanner *URLScanner) checkURL(job URLJob) *URLResult {
+// positive returns whether a status code indicates that something exists - either one of the positive status codes,
+// or one which a matcher looks for
+func (scanner *URLScanner) positive(code int) bool {
+	return containsInt(scanner.positiveStatusCodes, code) || containsInt(scanner.rules.matchedStatusCodes(), code)
+}
+
+func (scanner *URLScanner) checkURL(ctx context.Context, job URLJob) urlOutcome {
+
+	var outcome urlOutcome
 
 	job.URL = scanner.clean(job.URL)
 
-	if scanner.visited(job.URL) {
-		return nil
+	if scanner.visited(scanner.jobKey(job)) {
+		return outcome
+	}
+
+	if job.LinkDepth > 0 && scanner.pathCapped(job.URL) {
+		logrus.Debugf("Skipping %s as too many links to its path have been checked", job.URL)
+		return outcome
 	}
 
 	if scanner.busyChan != nil {
@@ -274,189 +691,148 @@ func (scanner *URLScanner) checkURL(job URLJob) *URLResult {
 
 	var code int
 	var location string
-	var result *URLResult
+	var failed error // set if the last attempt got no response, or was throttled
 
-	if err := retry.Do(func() error {
+	retries, err := scanner.retryPolicy.do(ctx, job.URL, func(last bool) error {
 
-		req, err := http.NewRequest(scanner.method, job.URL, nil)
+		outcome = urlOutcome{}
+		failed = nil
+
+		req, err := scanner.newRequest(ctx, job)
 		if err != nil {
 			return err
 		}
 
-		for _, header := range scanner.extraHeaders {
-			parts := strings.SplitN(header, ":", 2)
-			if len(parts) == 2 {
-				if strings.ToLower(parts[0]) == "host" {
-					req.Host = strings.TrimSpace(parts[1])
-				}
-				req.Header.Set(parts[0], strings.TrimPrefix(parts[1], " "))
-			}
+		if err := scanner.concurrency.acquire(ctx); err != nil {
+			return err
 		}
 
+		if err := scanner.limiter.wait(ctx, req.URL.Host); err != nil {
+			scanner.concurrency.release(0, err)
+			return err
+		}
+
+		started := time.Now()
 		resp, err := scanner.client.Do(req)
 		if err != nil {
-			return nil
+			scanner.concurrency.release(0, err)
+			failed = err
+			return err
 		}
 		defer func() { _ = resp.Body.Close() }()
 
+		if err := scanner.limiter.observe(req.URL.Host, resp); err != nil {
+			scanner.concurrency.release(0, err)
+			logrus.Debug(err)
+			failed = err
+			return err
+		}
+		scanner.concurrency.release(time.Since(started), nil)
+		scanner.failures.succeed()
+
+		if !last && scanner.retryPolicy.retryStatus(resp.StatusCode) {
+			return fmt.Errorf("%w: %s responded with %d", errServerError, job.URL, resp.StatusCode)
+		}
+
 		code = resp.StatusCode
 		location = resp.Header.Get("Location")
 
 		if location != "" {
 			if parsed, err := url.Parse(job.URL); err == nil {
 				if relative, err := url.Parse(location); err == nil {
-					target := parsed.ResolveReference(relative)
-					if target.Host == parsed.Host {
-						scanner.queue(URLJob{URL: target.String()})
+					if linked, ok := scanner.follow(job, parsed.ResolveReference(relative), "redirect"); ok {
+						outcome.jobs = append(outcome.jobs, linked)
 					}
 				}
 			}
 		}
 
-		for _, status := range scanner.positiveStatusCodes {
-			if status == code {
-				parsedURL, err := url.Parse(job.URL)
-				if err != nil {
-					return nil
-				}
+		if scanner.positive(code) {
+			parsedURL, err := url.Parse(job.URL)
+			if err != nil {
+				return nil
+			}
 
-				if !job.BasicOnly && !strings.Contains(job.URL, "/.htpasswd") && !strings.Contains(job.URL, "/.htaccess") {
-					for _, ext := range scanner.backupExtensions {
-						bUrl := job.URL + ext
-						if strings.Contains(job.URL, "?") {
-							bits := strings.SplitN(job.URL, "?", 2)
-							bUrl = strings.Join(bits, ext+"?")
-						}
-						scanner.queue(URLJob{URL: bUrl, BasicOnly: true})
-					}
+			body, size, err := readBody(resp)
+			if err != nil {
+				failed = err
+				return err
+			}
+			metadata := newResponseMetadata(resp, body, time.Since(started))
+
+			for _, length := range scanner.negativeLengths {
+				if length == size {
+					return nil
 				}
+			}
 
-				contentType := resp.Header.Get("Content-Type")
+			if !scanner.rules.accept(&Response{
+				StatusCode:       code,
+				Size:             size,
+				Header:           resp.Header,
+				Body:             body,
+				ResponseMetadata: metadata,
+			}) {
+				return nil
+			}
 
-				size := -1
+			if scanner.autoCalibrate && scanner.matchesBaseline(parsedURL, job.Values, code, body, size, metadata.Words, location) {
+				return nil
+			}
 
-				if scanner.enableSpidering && (contentType == "" || strings.Contains(contentType, "html")) {
-					body, err := ioutil.ReadAll(resp.Body)
-					if err == nil {
-						for _, link := range findLinks(job.URL, body) {
-							scanner.queue(URLJob{URL: link})
-						}
+			if !job.BasicOnly && !strings.Contains(job.URL, "/.htpasswd") && !strings.Contains(job.URL, "/.htaccess") {
+				for _, ext := range scanner.backupExtensions {
+					bUrl := job.URL + ext
+					if strings.Contains(job.URL, "?") {
+						bits := strings.SplitN(job.URL, "?", 2)
+						bUrl = strings.Join(bits, ext+"?")
 					}
-					size = len(body)
+					outcome.jobs = append(outcome.jobs, URLJob{URL: bUrl, BasicOnly: true, Depth: job.Depth, Values: job.Values, Source: "backup"})
 				}
+			}
 
-				if size == -1 {
-					contentLength := resp.Header.Get("Content-Length")
-					if contentLength != "" {
-						size, _ = strconv.Atoi(contentLength)
-					} else {
-						cdata, _ := ioutil.ReadAll(resp.Body)
-						size = len(cdata)
-						cdata = nil
+			if scanner.enableSpidering {
+				for _, link := range spider(job.URL, resp.Header, metadata.ContentType, body) {
+					target, err := url.Parse(link.URL)
+					if err != nil {
+						continue
 					}
-				}
-
-				for _, length := range scanner.negativeLengths {
-					if length == size {
-						return nil
+					if linked, ok := scanner.follow(job, target, link.Source); ok {
+						outcome.jobs = append(outcome.jobs, linked)
 					}
 				}
+			}
 
-				result = &URLResult{
-					StatusCode: code,
-					URL:        *parsedURL,
-					Size:       size,
-				}
+			outcome.result = &URLResult{
+				StatusCode:       code,
+				URL:              *parsedURL,
+				Size:             size,
+				Source:           job.Source,
+				ResponseMetadata: metadata,
+			}
+			if scanner.templatedRequest() {
+				outcome.result.Values = job.Values
+			}
 
-				break
+			if scanner.maxDepth > 0 && !scanner.templated() {
+				outcome.directory = scanner.recurse(job, parsedURL, code, location)
 			}
+
 		}
 
 		return nil
+	})
 
-	}, retry.Attempts(10), retry.DelayType(retry.BackOffDelay)); err != nil {
-		return nil
+	if retries > 0 {
+		logrus.Debugf("Checked %s with %d retries", job.URL, retries)
 	}
 
-	return result
-}
-
-var linkAttributes = []string{"src", "href"}
-
-func findLinks(currentURL string, html []byte) []string {
-
-	base, err := url.Parse(currentURL)
 	if err != nil {
-		return nil
-	}
-
-	var results []string
-
-	source := string(html)
-
-	var bestIndex int
-	var bestAttr string
-	var link string
-
-	for source != "" {
-		bestIndex = -1
-		for _, attr := range linkAttributes {
-			index := strings.Index(source, fmt.Sprintf("%s=", attr))
-			if index < bestIndex || bestIndex == -1 {
-				bestIndex = index
-				bestAttr = attr
-			}
-		}
-		if bestIndex == -1 {
-			break
+		if failed != nil && ctx.Err() == nil {
+			scanner.failures.fail(job.URL, failed)
 		}
-		source = source[bestIndex+len(bestAttr)+1:]
-		switch source[0] {
-		case '"':
-			source = source[1:]
-			index := strings.Index(source, "\"")
-			if index == -1 {
-				continue
-			}
-			link = source[:index]
-		case '\'':
-			source = source[1:]
-			index := strings.Index(source, "'")
-			if index == -1 {
-				continue
-			}
-			link = source[:index]
-		default:
-			spaceIndex := strings.Index(source, " ")
-			bIndex := strings.Index(source, ">")
-			if spaceIndex == -1 && bIndex == -1 {
-				continue
-			}
-			if spaceIndex == -1 {
-				link = source[:bIndex]
-			} else if bIndex == -1 {
-				link = source[:spaceIndex]
-			} else {
-				if spaceIndex < bIndex {
-					bIndex = spaceIndex
-				}
-				link = source[:bIndex]
-			}
-		}
-
-		u, err := url.Parse(link)
-		if err != nil {
-			return nil
-		}
-
-		target := base.ResolveReference(u)
-
-		if target.Host != base.Host {
-			continue
-		}
-
-		results = append(results, target.String())
+		return urlOutcome{}
 	}
 
-	return results
+	return outcome
 }
@@ -2,10 +2,20 @@ package scan
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/liamg/scout/pkg/wordlist"
 
@@ -34,6 +44,8 @@ func TestURLScanner(t *testing.T) {
 		WithTargetURL(*parsed),
 		WithParallelism(2),
 		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("login.php\nsomething.php")))),
+		// the words already have an extension, so must be requested as they are
+		WithIncludeNoExtension(true),
 	}
 
 	scanner := NewURLScanner(options...)
@@ -72,6 +84,8 @@ func TestURLScannerWithRedirects(t *testing.T) {
 		WithParallelism(1),
 		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("login.php")))),
 		WithPositiveStatusCodes([]int{http.StatusOK}),
+		// the words already have an extension, so must be requested as they are
+		WithIncludeNoExtension(true),
 	}
 
 	scanner := NewURLScanner(options...)
@@ -123,3 +137,768 @@ func TestURLScannerWithBackupFile(t *testing.T) {
 	assert.Equal(t, results[1].String(), server.URL+"/login.php~")
 
 }
+
+func TestURLScannerWithRecursion(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			http.Redirect(w, r, "/admin/", http.StatusMovedPermanently)
+		case "/admin/":
+			w.WriteHeader(http.StatusForbidden)
+		case "/admin/login.php":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := []URLOption{
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("admin\nlogin")))),
+		WithExtensions([]string{"php"}),
+		WithIncludeNoExtension(true),
+		WithBackupExtensions(nil),
+		WithPositiveStatusCodes([]int{http.StatusOK, http.StatusForbidden}),
+		WithRecursion(1),
+	}
+
+	scanner := NewURLScanner(options...)
+
+	results, err := scanner.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found []string
+	for _, result := range results {
+		found = append(found, result.String())
+	}
+
+	assert.ElementsMatch(t, []string{
+		server.URL + "/admin/",
+		server.URL + "/admin/login.php",
+	}, found)
+
+}
+
+func TestURLScannerWithRecursionAndDefaultExtensions(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			http.Redirect(w, r, "/admin/", http.StatusMovedPermanently)
+		case "/admin/":
+			w.WriteHeader(http.StatusForbidden)
+		case "/admin/login.php":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// words are only tried with extensions, so directories must be looked for separately to be recursed into
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("admin\nlogin")))),
+		WithBackupExtensions(nil),
+		WithRecursion(1),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	var found []string
+	for _, result := range results {
+		found = append(found, result.String())
+	}
+
+	assert.ElementsMatch(t, []string{
+		server.URL + "/admin/",
+		server.URL + "/admin/login.php",
+	}, found)
+}
+
+func TestURLScannerWithAutoCalibration(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.php":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("please log in to continue"))
+		default:
+			// soft 404 which reflects the requested path
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("the page " + r.URL.Path + " could not be found"))
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := []URLOption{
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("login\nsomething\nelse")))),
+		WithExtensions([]string{"php"}),
+		WithBackupExtensions(nil),
+		WithAutoCalibration(true),
+	}
+
+	scanner := NewURLScanner(options...)
+
+	baselines, err := scanner.Calibrate()
+	require.NoError(t, err)
+	require.NotEmpty(t, baselines)
+	assert.Equal(t, http.StatusOK, baselines[0].StatusCode)
+
+	results, err := scanner.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, results[0].String(), server.URL+"/login.php")
+
+}
+
+func TestURLScannerAutoCalibrationKeepsPagesWithSameWordCount(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/backup.php":
+			// as many words as the soft 404, but a different page
+			_, _ = w.Write([]byte("download the complete database backup here"))
+		default:
+			_, _ = w.Write([]byte("the page " + r.URL.Path + " could not be found"))
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(1),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("backup\nsomething\nelse")))),
+		WithExtensions([]string{"php"}),
+		WithBackupExtensions(nil),
+		WithAutoCalibration(true),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/backup.php", results[0].String())
+}
+
+func TestURLScannerAutoCalibrationWithFilenameInRecursedDirectory(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		case r.URL.Path == "/private/":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/private/backup/config.php":
+			_, _ = w.Write([]byte("<?php $password = 'hunter2';"))
+		case strings.HasPrefix(r.URL.Path, "/private/"):
+			// a soft 404 which differs from the one for the rest of the site
+			_, _ = w.Write([]byte("this private area has nothing at the requested location"))
+		default:
+			_, _ = w.Write([]byte("not found"))
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("backup\nsomething\nelse")))),
+		WithFilename("config.php"),
+		WithBackupExtensions(nil),
+		WithPositiveStatusCodes([]int{http.StatusOK, http.StatusForbidden}),
+		WithSeeding(true),
+		WithRecursion(1),
+		WithAutoCalibration(true),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	var found []string
+	for _, result := range results {
+		found = append(found, result.String())
+	}
+
+	// /private/ must be compared with its own baseline rather than the one for the root
+	assert.ElementsMatch(t, []string{
+		server.URL + "/private/",
+		server.URL + "/private/backup/config.php",
+	}, found)
+}
+
+func TestURLScannerWithCancellation(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/login.php":
+			w.WriteHeader(http.StatusOK)
+		default:
+			time.Sleep(time.Millisecond * 50)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	words := []string{"login"}
+	for i := 0; i < 1000; i++ {
+		words = append(words, fmt.Sprintf("nothing%d", i))
+	}
+
+	options := []URLOption{
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(strings.NewReader(strings.Join(words, "\n")))),
+		WithExtensions([]string{"php"}),
+		WithBackupExtensions(nil),
+	}
+
+	scanner := NewURLScanner(options...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+
+	started := time.Now()
+	results, err := scanner.ScanContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(started) < time.Second*5)
+
+	var found []string
+	for _, result := range results {
+		found = append(found, result.String())
+	}
+	assert.Contains(t, found, server.URL+"/login.php")
+
+}
+
+func TestURLScannerResumeFromState(t *testing.T) {
+
+	var words []string
+	for i := 0; i < 200; i++ {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 100 {
+			cancel()
+		}
+		switch r.URL.Path {
+		case "/word10/", "/word150/":
+			w.WriteHeader(http.StatusForbidden)
+		case "/word10", "/word150":
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+		case "/word10/word190.php", "/word150/word5.php", "/word199.php":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := func() []URLOption {
+		return []URLOption{
+			WithTargetURL(*parsed),
+			WithParallelism(4),
+			WithWordlist(wordlist.FromReader(strings.NewReader(strings.Join(words, "\n")))),
+			WithExtensions([]string{"php"}),
+			WithIncludeNoExtension(true),
+			WithBackupExtensions(nil),
+			WithPositiveStatusCodes([]int{http.StatusOK, http.StatusForbidden}),
+			WithRecursion(1),
+		}
+	}
+
+	scanner := NewURLScanner(options()...)
+	_, err = scanner.ScanContext(ctx)
+	require.Equal(t, context.Canceled, err)
+
+	state := scanner.State()
+	assert.True(t, state.WordlistPosition > 0)
+	assert.True(t, state.WordlistPosition < int64(len(words)))
+
+	resumed := NewURLScanner(append(options(), WithState(state))...)
+	results, err := resumed.Scan()
+	require.NoError(t, err)
+
+	var found []string
+	for _, result := range results {
+		found = append(found, result.String())
+	}
+
+	assert.ElementsMatch(t, []string{
+		server.URL + "/word10/",
+		server.URL + "/word150/",
+		server.URL + "/word10/word190.php",
+		server.URL + "/word150/word5.php",
+		server.URL + "/word199.php",
+	}, found)
+	assert.Empty(t, resumed.State().Queue)
+}
+
+func TestURLScannerResumeWithBloomVisitedSet(t *testing.T) {
+
+	var words []string
+	for i := 0; i < 200; i++ {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 100 {
+			cancel()
+		}
+		switch r.URL.Path {
+		case "/word5.php", "/word199.php":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	options := func() []URLOption {
+		return []URLOption{
+			WithTargetURL(*parsed),
+			WithParallelism(4),
+			WithWordlist(wordlist.FromReader(strings.NewReader(strings.Join(words, "\n")))),
+			WithExtensions([]string{"php"}),
+			WithBackupExtensions(nil),
+			WithVisitedSet(NewBloomVisitedSet(1000, 0.001)),
+		}
+	}
+
+	scanner := NewURLScanner(options()...)
+	_, err = scanner.ScanContext(ctx)
+	require.Equal(t, context.Canceled, err)
+
+	state := scanner.State()
+
+	resumed := NewURLScanner(append(options(), WithState(state))...)
+	results, err := resumed.Scan()
+	require.NoError(t, err)
+
+	// there is no journal of the requests already made, so the results found before stopping must not be found again
+	var found []string
+	for _, result := range results {
+		found = append(found, result.String())
+	}
+	assert.ElementsMatch(t, []string{server.URL + "/word5.php", server.URL + "/word199.php"}, found)
+}
+
+func TestURLScannerResumeWithVisitedJournal(t *testing.T) {
+
+	var words []string
+	for i := 0; i < 200; i++ {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+
+	// the journal is replayed into a new set of whichever kind, which must remember what the old one did
+	sets := map[string]func(t *testing.T) VisitedSet{
+		"map": func(t *testing.T) VisitedSet {
+			return NewMapVisitedSet()
+		},
+		"bloom": func(t *testing.T) VisitedSet {
+			return NewBloomVisitedSet(1000, 0.001)
+		},
+		"disk": func(t *testing.T) VisitedSet {
+			set, err := NewDiskVisitedSet(t.TempDir())
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = set.Close() })
+			return set
+		},
+	}
+
+	for name, newSet := range sets {
+		t.Run(name, func(t *testing.T) {
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var mutex sync.Mutex
+			requests := make(map[string]int)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				requests[r.URL.Path]++
+				if len(requests) == 150 {
+					cancel()
+				}
+				mutex.Unlock()
+				if strings.HasPrefix(r.URL.Path, "/word") {
+					// every page links to the same one, which should only be requested once however often it is found
+					w.Header().Set("Content-Type", "text/html")
+					_, _ = w.Write([]byte(`<a href="/common">common</a>`))
+					return
+				}
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			parsed, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			path := filepath.Join(t.TempDir(), "visited")
+
+			options := func(journal *VisitedJournal) []URLOption {
+				return []URLOption{
+					WithTargetURL(*parsed),
+					WithParallelism(4),
+					WithWordlist(wordlist.FromReader(strings.NewReader(strings.Join(words, "\n")))),
+					WithExtensions(nil),
+					WithIncludeNoExtension(true),
+					WithBackupExtensions(nil),
+					WithSeeding(false),
+					WithSpidering(true),
+					WithVisitedSet(newSet(t)),
+					WithVisitedJournal(journal),
+				}
+			}
+
+			journal, err := OpenVisitedJournal(path)
+			require.NoError(t, err)
+			scanner := NewURLScanner(options(journal)...)
+			_, err = scanner.ScanContext(ctx)
+			require.Equal(t, context.Canceled, err)
+			state := scanner.State()
+			require.NoError(t, journal.Close())
+
+			mutex.Lock()
+			require.Equal(t, 1, requests["/common"])
+			mutex.Unlock()
+
+			journal, err = OpenVisitedJournal(path)
+			require.NoError(t, err)
+			resumed := NewURLScanner(append(options(journal), WithState(state))...)
+			results, err := resumed.Scan()
+			require.NoError(t, err)
+			require.NoError(t, journal.Close())
+
+			assert.Equal(t, len(words), len(results))
+			mutex.Lock()
+			assert.Equal(t, 1, requests["/common"])
+			mutex.Unlock()
+		})
+	}
+}
+
+func TestURLScannerResponseMetadata(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.php":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Server", "test-server")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("<html>\n<title>Log &amp; in</title>\n<body>hello world</body>\n</html>\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	resultChan := make(chan URLResult, 1)
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithResultChan(resultChan),
+		WithParallelism(1),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("login.php")))),
+		WithIncludeNoExtension(true),
+	)
+
+	_, err = scanner.Scan()
+	require.NoError(t, err)
+
+	var results []URLResult
+	for result := range resultChan {
+		results = append(results, result)
+	}
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, "text/html", results[0].ContentType)
+	assert.Equal(t, "test-server", results[0].Server)
+	assert.Equal(t, "Log & in", results[0].Title)
+	assert.Equal(t, 4, results[0].Lines)
+	assert.Equal(t, 7, results[0].Words)
+	assert.NotEmpty(t, results[0].BodyHash)
+}
+
+func TestURLScannerWithMatchersAndFilters(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			_, _ = w.Write([]byte("admin panel"))
+		case "/login":
+			_, _ = w.Write([]byte("please log in"))
+		case "/error":
+			_, _ = w.Write([]byte("something went wrong with the admin panel"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("admin\nlogin\nerror\nmissing")))),
+		WithIncludeNoExtension(true),
+		WithExtensions(nil),
+		WithMatchers(ModeAnd, RegexMatcher(regexp.MustCompile("admin"))),
+		WithFilters(ModeOr, WordsMatcher(Range{Min: 5, Max: 10})),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/admin", results[0].String())
+}
+
+func TestURLScannerMatchStatusOutsidePositiveCodes(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			w.WriteHeader(http.StatusTeapot)
+		case "/login":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// 418 is not a positive status code, but is what the matcher asks for
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(1),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("admin\nlogin\nmissing")))),
+		WithIncludeNoExtension(true),
+		WithExtensions(nil),
+		WithPositiveStatusCodes([]int{http.StatusOK}),
+		WithMatchers(ModeOr, StatusMatcher(http.StatusTeapot)),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/admin", results[0].String())
+}
+
+func TestURLScannerWithFuzzKeywordInQuery(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/users" && r.URL.Query().Get("id") == "admin" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL + "/api/users?id=" + FuzzKeyword)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("guest\nadmin\nroot")))),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/api/users?id=admin", results[0].String())
+}
+
+func TestURLScannerWithFuzzKeywordInPath(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/files/backup.txt" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL + "/files/" + FuzzKeyword)
+	require.NoError(t, err)
+
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("backup\nsecret")))),
+		WithExtensions([]string{"txt"}),
+		WithBackupExtensions(nil),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/files/backup.txt", results[0].String())
+}
+
+func TestURLScannerWithFuzzKeywordInRequest(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPost && r.Header.Get("X-Role") == "editor" && string(body) == "role=editor" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL + "/login")
+	require.NoError(t, err)
+
+	resultChan := make(chan URLResult, 1)
+	scanner := NewURLScanner(
+		WithTargetURL(*parsed),
+		WithResultChan(resultChan),
+		WithParallelism(2),
+		WithWordlist(wordlist.FromReader(bytes.NewReader([]byte("viewer\neditor\nowner")))),
+		WithMethod("post"),
+		WithExtraHeaders([]string{"X-Role: " + FuzzKeyword}),
+		WithBody("role="+FuzzKeyword),
+	)
+
+	_, err = scanner.Scan()
+	require.NoError(t, err)
+
+	var results []URLResult
+	for result := range resultChan {
+		results = append(results, result)
+	}
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, server.URL+"/login", results[0].URL.String())
+	assert.Equal(t, map[string]string{FuzzKeyword: "editor"}, results[0].Values)
+}
+
+func TestURLScannerWithCombinedWordlists(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bob/notes", "/carol/backup":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL + "/USER/FILE")
+	require.NoError(t, err)
+
+	lists := func() []wordlist.KeywordList {
+		return []wordlist.KeywordList{
+			{Keyword: "USER", Wordlist: wordlist.FromReader(bytes.NewReader([]byte("alice\nbob\ncarol")))},
+			{Keyword: "FILE", Wordlist: wordlist.FromReader(bytes.NewReader([]byte("backup\nnotes")))},
+		}
+	}
+
+	t.Run("clusterbomb", func(t *testing.T) {
+		scanner := NewURLScanner(
+			WithTargetURL(*parsed),
+			WithParallelism(2),
+			WithWordlist(wordlist.ClusterBomb(lists()...)),
+			WithIncludeNoExtension(true),
+			WithExtensions(nil),
+			WithBackupExtensions(nil),
+		)
+		results, err := scanner.Scan()
+		require.NoError(t, err)
+
+		var found []string
+		for _, result := range results {
+			found = append(found, result.Path)
+		}
+		sort.Strings(found)
+		assert.Equal(t, []string{"/bob/notes", "/carol/backup"}, found)
+	})
+
+	t.Run("pitchfork", func(t *testing.T) {
+		scanner := NewURLScanner(
+			WithTargetURL(*parsed),
+			WithParallelism(2),
+			WithWordlist(wordlist.Pitchfork(lists()...)),
+			WithIncludeNoExtension(true),
+			WithExtensions(nil),
+			WithBackupExtensions(nil),
+		)
+		results, err := scanner.Scan()
+		require.NoError(t, err)
+
+		require.Equal(t, 1, len(results))
+		assert.Equal(t, "/bob/notes", results[0].Path)
+	})
+}
@@ -0,0 +1,153 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// vhostBaseline is a cluster of alike responses given for vhosts which do not exist
+type vhostBaseline struct {
+	statusCode int
+	location   string // redirect target, with the requested vhost replaced by {vhost}
+	minSize    int
+	maxSize    int
+	minWords   int
+	maxWords   int
+	simhashes  []uint64
+}
+
+// vhostResponse is what a response is compared to the baselines by
+type vhostResponse struct {
+	statusCode int
+	location   string
+	size       int
+	words      int
+	simhash    uint64
+}
+
+func newVHOSTResponse(vhost string, resp *http.Response, body []byte, size int) vhostResponse {
+	return vhostResponse{
+		statusCode: resp.StatusCode,
+		location:   strings.ReplaceAll(resp.Header.Get("Location"), vhost, "{vhost}"),
+		size:       size,
+		words:      len(bytes.Fields(body)),
+		simhash:    simhash(body),
+	}
+}
+
+func (b *vhostBaseline) String() string {
+	str := fmt.Sprintf("[%d] size=%d-%d words=%d-%d", b.statusCode, b.minSize, b.maxSize, b.minWords, b.maxWords)
+	if b.location != "" {
+		str += " -> " + b.location
+	}
+	return str
+}
+
+// matches returns whether a response has the status code and redirect of the baseline, and, if contents are compared,
+// a body like one of those in the cluster
+func (b *vhostBaseline) matches(response vhostResponse, similarity Similarity, compareContents bool) bool {
+	if b.statusCode != response.statusCode || b.location != response.location {
+		return false
+	}
+	if !compareContents {
+		return true
+	}
+	if !similarity.within(response.size, b.minSize, b.maxSize) || !similarity.within(response.words, b.minWords, b.maxWords) {
+		return false
+	}
+	for _, hash := range b.simhashes {
+		if distance(hash, response.simhash) <= similarity.Distance {
+			return true
+		}
+	}
+	return false
+}
+
+// add widens the cluster to include a response
+func (b *vhostBaseline) add(response vhostResponse) {
+	if response.size < b.minSize {
+		b.minSize = response.size
+	}
+	if response.size > b.maxSize {
+		b.maxSize = response.size
+	}
+	if response.words < b.minWords {
+		b.minWords = response.words
+	}
+	if response.words > b.maxWords {
+		b.maxWords = response.words
+	}
+	b.simhashes = append(b.simhashes, response.simhash)
+}
+
+// calibrate requests random vhosts which should not exist from a target, clustering alike responses into baselines
+func (scanner *VHOSTScanner) calibrate(ctx context.Context, target *vhostTarget) error {
+
+	target.baselines = nil
+
+	for i := 0; i < scanner.options.Baselines; i++ {
+		word, err := randomWord()
+		if err != nil {
+			return err
+		}
+		response, err := scanner.fetchBaseline(ctx, target, word+"."+scanner.options.BaseDomain)
+		if err != nil {
+			return err
+		}
+		if baseline := target.matchingBaseline(response, scanner.options.Similarity, scanner.options.ContentHashing); baseline != nil {
+			baseline.add(response)
+			continue
+		}
+		target.baselines = append(target.baselines, &vhostBaseline{
+			statusCode: response.statusCode,
+			location:   response.location,
+			minSize:    response.size,
+			maxSize:    response.size,
+			minWords:   response.words,
+			maxWords:   response.words,
+			simhashes:  []uint64{response.simhash},
+		})
+	}
+
+	for _, baseline := range target.baselines {
+		logrus.Debugf("VHOST baseline for %s: %s", target.origin, baseline)
+	}
+
+	return nil
+}
+
+func (scanner *VHOSTScanner) fetchBaseline(ctx context.Context, target *vhostTarget, vhost string) (vhostResponse, error) {
+
+	req, err := scanner.newRequest(ctx, target, vhost)
+	if err != nil {
+		return vhostResponse{}, err
+	}
+
+	resp, err := scanner.limiter.do(target.client, req, target.ip)
+	if err != nil {
+		return vhostResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, size, err := readBody(resp)
+	if err != nil {
+		return vhostResponse{}, err
+	}
+
+	return newVHOSTResponse(vhost, resp, body, size), nil
+}
+
+// matchingBaseline returns the baseline a response from the target looks like, if any
+func (target *vhostTarget) matchingBaseline(response vhostResponse, similarity Similarity, compareContents bool) *vhostBaseline {
+	for _, baseline := range target.baselines {
+		if baseline.matches(response, similarity, compareContents) {
+			return baseline
+		}
+	}
+	return nil
+}
@@ -2,12 +2,26 @@ package scan
 
 import (
 	"bytes"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/liamg/scout/internal/app/scout/data"
 	"github.com/liamg/scout/pkg/wordlist"
 )
 
+// VHOSTOption configures a VHOSTScanner. A *VHOSTOptions is also a VHOSTOption, which replaces everything configured
+// before it, so scanners can still be configured with the struct alone.
+type VHOSTOption interface {
+	apply(opt *VHOSTOptions)
+}
+
+type vhostOptionFunc func(opt *VHOSTOptions)
+
+func (f vhostOptionFunc) apply(opt *VHOSTOptions) {
+	f(opt)
+}
+
 type VHOSTOptions struct {
 	BaseDomain          string           // target url
 	Timeout             time.Duration    // http request timeout
@@ -15,21 +29,70 @@ type VHOSTOptions struct {
 	ResultChan          chan VHOSTResult // chan to return results on - otherwise will be returned in slice
 	BusyChan            chan string      // chan to use to update current job
 	Wordlist            wordlist.Wordlist
-	SkipSSLVerification bool
-	UseSSL              bool
-	IP                  string
-	Port                int
-	ContentHashing      bool
+	SkipSSLVerification bool        // Deprecated: certificates are only verified if VerifySSL is set
+	VerifySSL           bool        // verify certificates, which is off by default as guessed vhosts rarely match them
+	UseSSL              bool        // use HTTPS, unless Scheme is set
+	Scheme              Scheme      // whether to use HTTP, HTTPS, both or whichever each port speaks
+	IP                  string      // address to send every request to - the base domain's is looked up otherwise
+	IPs                 []string    // more addresses or CIDR ranges to check every vhost against, as well as IP
+	AllIPs              bool        // check every vhost against all of the base domain's addresses, rather than the first
+	Port                int         // port to connect to - 80, or 443 with SSL, otherwise
+	Ports               []int       // more ports to check every vhost on, as well as Port
+	ContentHashing      bool        // compare response bodies with the baselines, rather than only status codes
+	Baselines           int         // how many random vhosts to request to learn what a vhost which does not exist looks like
+	Similarity          Similarity  // how alike a body must be to a baseline's to be hidden - defaults to DefaultSimilarity
+	State               *VHOSTState // resume a previous scan from a snapshot taken with VHOSTScanner.State
+	Matchers            []Matcher   // only report responses which satisfy these, combined according to MatchMode
+	MatchMode           Mode
+	Filters             []Matcher // hide responses which satisfy these, combined according to FilterMode
+	FilterMode          Mode
+	RateLimit           RateLimit           // limits how quickly requests are sent
+	ThrottleChan        chan ThrottleEvent  // chan to report the server asking for requests to slow down, see WithThrottleChan
+	AdaptiveConcurrency AdaptiveConcurrency // grow and shrink how many requests are in flight, starting from Parallelism
+	ErrorChan           chan ScanError      // chan to report requests which failed without a response, see WithErrorChan
+	ErrorBudget         int                 // abort after this many requests in a row fail - 0 is unlimited
+	RetryPolicy         RetryPolicy         // which failed requests are sent again - defaults to DefaultRetryPolicy
+	HTTPClient          *http.Client        // send requests with a copy of this client, which does not follow redirects
+	Transport           http.RoundTripper   // send requests with this, or the HTTPClient's transport - copied if an *http.Transport so requests can be sent to IP, otherwise it must do so itself
+	Method              string              // defaults to GET
+	Headers             []string            // extra headers to send, as "Name: value" - except Host, which is always the vhost
+	Proxy               *url.URL            // send requests through this HTTP proxy, which must allow CONNECT to the IP
+	PositiveStatusCodes []int               // only report responses with these status codes - any if empty
+	NegativeStatusCodes []int               // never report responses with these status codes
+	NegativeLengths     []int               // never report responses with these content lengths
 }
 
 type VHOSTResult struct {
 	VHOST      string
+	IP         string // address the vhost was found on
+	Origin     string // scheme, IP and port the vhost was found on, e.g. https://10.0.0.1:8443
 	StatusCode int
+	Size       int
+	ResponseMetadata
 }
 
 var DefaultVHOSTOptions = VHOSTOptions{
 	Timeout:     time.Second * 5,
 	Parallelism: 10,
+	Method:      http.MethodGet,
+	Baselines:   3,
+}
+
+func (opt *VHOSTOptions) apply(target *VHOSTOptions) {
+	if opt != nil {
+		*target = *opt
+	}
+}
+
+// transport returns the transport requests should be sent with, or nil for the scanner to build its own
+func (opt *VHOSTOptions) transport() http.RoundTripper {
+	if opt.Transport != nil {
+		return opt.Transport
+	}
+	if opt.HTTPClient != nil {
+		return opt.HTTPClient.Transport
+	}
+	return nil
 }
 
 func (opt *VHOSTOptions) Inherit() {
@@ -39,6 +102,18 @@ func (opt *VHOSTOptions) Inherit() {
 	if opt.Parallelism == 0 {
 		opt.Parallelism = DefaultVHOSTOptions.Parallelism
 	}
+	if opt.Method == "" {
+		opt.Method = DefaultVHOSTOptions.Method
+	}
+	if opt.Baselines <= 0 {
+		opt.Baselines = DefaultVHOSTOptions.Baselines
+	}
+	if opt.Similarity == (Similarity{}) {
+		opt.Similarity = DefaultSimilarity
+	}
+	if opt.RetryPolicy.Attempts <= 0 {
+		opt.RetryPolicy = DefaultRetryPolicy
+	}
 	if opt.Wordlist == nil {
 		wordlistBytes, err := data.Asset("assets/vhost.txt")
 		if err != nil {
@@ -47,3 +122,244 @@ func (opt *VHOSTOptions) Inherit() {
 		opt.Wordlist = wordlist.FromReader(bytes.NewReader(wordlistBytes))
 	}
 }
+
+// WithVHOSTBaseDomain sets the domain which vhosts are guessed as subdomains of
+func WithVHOSTBaseDomain(domain string) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.BaseDomain = domain
+	})
+}
+
+// WithVHOSTIP sets the address to send every request to - the base domain's is looked up otherwise
+func WithVHOSTIP(ip string) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.IP = ip
+	})
+}
+
+// WithVHOSTIPs checks every vhost against each of these addresses or CIDR ranges, finding vhosts which exist on only
+// some nodes of a pool
+func WithVHOSTIPs(ips ...string) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.IPs = append(opt.IPs, ips...)
+	})
+}
+
+// WithVHOSTAllIPs checks every vhost against all of the base domain's addresses, rather than only the first
+func WithVHOSTAllIPs(all bool) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.AllIPs = all
+	})
+}
+
+// WithVHOSTPort sets the port to connect to - 80, or 443 with SSL, otherwise
+func WithVHOSTPort(port int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Port = port
+	})
+}
+
+// WithVHOSTPorts checks every vhost on each of these ports
+func WithVHOSTPorts(ports ...int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Ports = append(opt.Ports, ports...)
+	})
+}
+
+// WithVHOSTScheme sets whether to use HTTP, HTTPS, both, or whichever each port completes a TLS handshake with
+func WithVHOSTScheme(scheme Scheme) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Scheme = scheme
+	})
+}
+
+// WithVHOSTSSL sends requests over HTTPS
+func WithVHOSTSSL(useSSL bool) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.UseSSL = useSSL
+	})
+}
+
+// WithVHOSTSSLVerification verifies certificates, which is off by default as guessed vhosts rarely match them
+func WithVHOSTSSLVerification(verify bool) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.VerifySSL = verify
+	})
+}
+
+func WithVHOSTTimeout(timeout time.Duration) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Timeout = timeout
+	})
+}
+
+func WithVHOSTParallelism(routines int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Parallelism = routines
+	})
+}
+
+func WithVHOSTResultChan(c chan VHOSTResult) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.ResultChan = c
+	})
+}
+
+func WithVHOSTBusyChan(c chan string) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.BusyChan = c
+	})
+}
+
+func WithVHOSTWordlist(w wordlist.Wordlist) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Wordlist = w
+	})
+}
+
+// WithVHOSTContentHashing reports vhosts whose response body is unlike those of vhosts which do not exist, even if the
+// status code is the same
+func WithVHOSTContentHashing(hashing bool) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.ContentHashing = hashing
+	})
+}
+
+// WithVHOSTBaselines sets how many random vhosts are requested to learn what a vhost which does not exist looks like
+func WithVHOSTBaselines(count int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Baselines = count
+	})
+}
+
+// WithVHOSTSimilarity sets how alike a response body must be to a baseline's to be hidden, with content hashing
+func WithVHOSTSimilarity(similarity Similarity) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Similarity = similarity
+	})
+}
+
+// WithVHOSTState resumes a previous scan from a snapshot taken with VHOSTScanner.State
+func WithVHOSTState(state *VHOSTState) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.State = state
+	})
+}
+
+// WithVHOSTMatchers only reports responses which satisfy the matchers, combined according to mode
+func WithVHOSTMatchers(mode Mode, matchers ...Matcher) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.MatchMode = mode
+		opt.Matchers = append(opt.Matchers, matchers...)
+	})
+}
+
+// WithVHOSTFilters hides responses which satisfy the filters, combined according to mode
+func WithVHOSTFilters(mode Mode, filters ...Matcher) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.FilterMode = mode
+		opt.Filters = append(opt.Filters, filters...)
+	})
+}
+
+// WithVHOSTPositiveStatusCodes only reports responses with these status codes
+func WithVHOSTPositiveStatusCodes(codes []int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.PositiveStatusCodes = codes
+	})
+}
+
+// WithVHOSTNegativeStatusCodes never reports responses with these status codes
+func WithVHOSTNegativeStatusCodes(codes []int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.NegativeStatusCodes = codes
+	})
+}
+
+// WithVHOSTNegativeLengths never reports responses with these content lengths
+func WithVHOSTNegativeLengths(lengths []int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.NegativeLengths = lengths
+	})
+}
+
+// WithVHOSTMethod sets the HTTP method to use
+func WithVHOSTMethod(method string) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Method = method
+	})
+}
+
+// WithVHOSTHeaders adds extra headers to send, as "Name: value"
+func WithVHOSTHeaders(headers []string) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Headers = append(opt.Headers, headers...)
+	})
+}
+
+// WithVHOSTProxy sends requests through an HTTP proxy, which must allow CONNECT to the IP and port so that the vhost is
+// not looked up by the proxy itself
+func WithVHOSTProxy(proxy *url.URL) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Proxy = proxy
+	})
+}
+
+// WithVHOSTRateLimit limits how quickly requests are sent
+func WithVHOSTRateLimit(limit RateLimit) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.RateLimit = limit
+	})
+}
+
+// WithVHOSTThrottleChan reports each time the server asks for requests to slow down, as WithThrottleChan does
+func WithVHOSTThrottleChan(c chan ThrottleEvent) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.ThrottleChan = c
+	})
+}
+
+// WithVHOSTAdaptiveConcurrency grows and shrinks how many requests are in flight according to how the server copes,
+// starting from the parallelism
+func WithVHOSTAdaptiveConcurrency(adaptive AdaptiveConcurrency) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.AdaptiveConcurrency = adaptive
+	})
+}
+
+// WithVHOSTErrorChan reports each request which fails without a response, as WithErrorChan does
+func WithVHOSTErrorChan(c chan ScanError) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.ErrorChan = c
+	})
+}
+
+// WithVHOSTErrorBudget aborts the scan with ErrTargetUnreachable once this many requests in a row have failed
+func WithVHOSTErrorBudget(budget int) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.ErrorBudget = budget
+	})
+}
+
+// WithVHOSTRetryPolicy decides which failed requests are sent again, and how long to wait in between
+func WithVHOSTRetryPolicy(policy RetryPolicy) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.RetryPolicy = policy
+	})
+}
+
+// WithVHOSTHTTPClient sends requests with a copy of client, which does not follow redirects. Its transport is copied
+// if it is an *http.Transport, so that requests can be sent to the IP.
+func WithVHOSTHTTPClient(client *http.Client) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.HTTPClient = client
+	})
+}
+
+// WithVHOSTTransport sends requests with transport, which is copied if it is an *http.Transport so that requests can be
+// sent to the IP. Any other transport must send them to the right address itself.
+func WithVHOSTTransport(transport http.RoundTripper) VHOSTOption {
+	return vhostOptionFunc(func(opt *VHOSTOptions) {
+		opt.Transport = transport
+	})
+}
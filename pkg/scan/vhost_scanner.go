@@ -3,147 +3,429 @@ package scan
 import (
 	"context"
 	"crypto/md5"
-	"crypto/tls"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/avast/retry-go"
+	"github.com/liamg/scout/pkg/wordlist"
+
 	"github.com/sirupsen/logrus"
 )
 
 type VHOSTScanner struct {
-	client  *http.Client
-	options *VHOSTOptions
-	badCode int
-	badHash string
+	client        *http.Client
+	options       *VHOSTOptions
+	stateMutex    sync.RWMutex      // held for writing while taking a snapshot of the scan state, and for reading while changing it
+	progressMutex sync.Mutex        // guards pending and results
+	pending       map[uint64]string // vhosts queued or in progress
+	lastJobID     uint64
+	wordPosition  int64 // how many words have been read from the wordlist and loaded as jobs
+	results       []VHOSTResult
+	resumedJobs   []vhostJob
+	rules         responseRules
+	limiter       *limiter
+	transport     *http.Transport // copied for each target to send requests to its origin - nil if a custom transport could not be copied
+	concurrency   *concurrency    // nil unless adaptive
+	failures      *failures
+	targets       []*vhostTarget // origins every vhost is checked against
+}
+
+// vhostTarget is an origin vhosts are checked against, with a client which sends every request to it
+type vhostTarget struct {
+	ip        string
+	port      int
+	ssl       bool
+	origin    string // e.g. https://10.0.0.1:8443
+	client    *http.Client
+	baselines []*vhostBaseline // responses given for vhosts which do not exist
 }
 
-func NewVHOSTScanner(opt *VHOSTOptions) *VHOSTScanner {
+type vhostJob struct {
+	id    uint64
+	vhost string
+}
+
+// NewVHOSTScanner creates a scanner configured by options, which may include a *VHOSTOptions
+func NewVHOSTScanner(options ...VHOSTOption) *VHOSTScanner {
 
-	if opt == nil {
-		opt = &DefaultVHOSTOptions
+	opt := &VHOSTOptions{}
+	for _, option := range options {
+		if option != nil {
+			option.apply(opt)
+		}
 	}
 
 	opt.Inherit()
 
-	client := &http.Client{
-		Timeout:   opt.Timeout,
-		Transport: http.DefaultTransport,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
+	for _, header := range opt.Headers {
+		if isHostHeader(header) {
+			logrus.Warnf("Ignoring header '%s', as the Host is always the vhost being checked", header)
+		}
+	}
+
+	scanner := &VHOSTScanner{
+		options:  opt,
+		pending:  make(map[uint64]string),
+		limiter:  newLimiter(opt.RateLimit, opt.ThrottleChan),
+		failures: newFailures(opt.ErrorBudget, opt.ErrorChan),
+		rules: responseRules{
+			matchers:   opt.Matchers,
+			matchMode:  opt.MatchMode,
+			filters:    opt.Filters,
+			filterMode: opt.FilterMode,
 		},
 	}
 
-	client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	if opt.AdaptiveConcurrency.enabled() {
+		scanner.concurrency = newConcurrency(opt.AdaptiveConcurrency, opt.Parallelism)
+	}
+
+	// the transport is copied if possible, so that requests can be sent to the IP without affecting anything else
+	var transport http.RoundTripper
+	switch given := opt.transport().(type) {
+	case nil:
+		scanner.transport = newTransport(scanner.workers(), !opt.VerifySSL)
+		transport = scanner.transport
+	case *http.Transport:
+		scanner.transport = given.Clone()
+		transport = scanner.transport
+	default:
+		transport = given
+	}
+	if scanner.transport != nil {
+		// every connection is dialled to the IP, tunnelling through opt.Proxy if there is one, so a proxy from the
+		// environment must not be used as well - the request meant for it would be sent to the IP instead
+		scanner.transport.Proxy = nil
+	}
+	scanner.client = newClient(opt.HTTPClient, transport, opt.Timeout)
+	scanner.client.Transport = transport
 
-	return &VHOSTScanner{
-		options: opt,
-		client:  client,
+	if opt.State != nil {
+		scanner.restore(opt.State)
 	}
+
+	return scanner
 }
 
-func (scanner *VHOSTScanner) forceRequestsToIP(ip net.IP) {
-	dialer := &net.Dialer{
-		Timeout: scanner.options.Timeout,
+// newTarget creates a target with a client which sends every request to ip and port, whatever its vhost. Each has its
+// own copy of the transport, as connections are pooled by vhost.
+func (scanner *VHOSTScanner) newTarget(ip net.IP, port int, ssl bool) *vhostTarget {
+	target := &vhostTarget{
+		ip:     ip.String(),
+		port:   port,
+		ssl:    ssl,
+		origin: origin(ip.String(), port, ssl),
+		client: scanner.client,
 	}
-	scanner.client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		port := scanner.options.Port
-		if port == 0 {
-			if scanner.options.UseSSL {
-				port = 443
-			} else {
-				port = 80
-			}
-		}
-		return dialer.DialContext(ctx, network, fmt.Sprintf("%s:%d", ip, port))
+	if scanner.transport == nil {
+		logrus.Debugf("Requests cannot be forced to %s, so are sent wherever the custom transport sends them", target.origin)
+		return target
+	}
+	address := net.JoinHostPort(target.ip, strconv.Itoa(port))
+	transport := scanner.transport.Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return scanner.dial(ctx, address)
 	}
+	client := *scanner.client
+	client.Transport = transport
+	target.client = &client
+	return target
 }
 
-func md5Hash(input string) string {
-	hash := md5.New()
-	io.WriteString(hash, input)
-	return fmt.Sprintf("%x", hash.Sum(nil))
+// close closes the target's idle connections, if it has its own
+func (target *vhostTarget) close(shared *http.Client) {
+	if target.client != shared {
+		target.client.CloseIdleConnections()
+	}
 }
 
-func (scanner *VHOSTScanner) Scan() ([]string, error) {
+// closeTargets closes the idle connections of the previous scan's targets
+func (scanner *VHOSTScanner) closeTargets() {
+	for _, target := range scanner.targets {
+		target.close(scanner.client)
+	}
+}
 
-	logrus.Debug("Looking up base domain...")
+// resolveTargets returns an origin for each combination of IP, port and scheme, probing and calibrating as many at once
+// as there are workers. Those which cannot be connected to or calibrated in time are skipped with a warning, unless none
+// can be.
+func (scanner *VHOSTScanner) resolveTargets(ctx context.Context) ([]*vhostTarget, error) {
+
+	ips, err := scanner.resolveIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := scanner.options.endpoints()
+	if len(ips)*len(endpoints) > 1 && scanner.transport == nil {
+		return nil, fmt.Errorf("a custom transport which is not an *http.Transport can only send requests to one origin")
+	}
+
+	type candidate struct {
+		ip       net.IP
+		endpoint endpoint
+	}
+	var candidates []candidate
+	for _, ip := range ips {
+		for _, endpoint := range endpoints {
+			candidates = append(candidates, candidate{ip: ip, endpoint: endpoint})
+		}
+	}
+
+	resolved := make([]*vhostTarget, len(candidates))
+	errs := make([]error, len(candidates))
+	slots := make(chan struct{}, scanner.workers())
+	wg := sync.WaitGroup{}
+	for i, c := range candidates {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, c candidate) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			resolved[i], errs[i] = scanner.resolveTarget(ctx, c.ip, c.endpoint)
+		}(i, c)
+	}
+	wg.Wait()
+
+	var targets []*vhostTarget
+	var lastErr error
+	for i, target := range resolved {
+		if target != nil {
+			targets = append(targets, target)
+		} else if errs[i] != nil {
+			lastErr = errs[i]
+		}
+	}
 
-	ip := scanner.options.IP
-	if ip != "" {
-		if parsed := net.ParseIP(ip); parsed == nil {
-			return nil, fmt.Errorf("invalid IP address specified: %s", ip)
+	if ctx.Err() != nil {
+		for _, target := range targets {
+			target.close(scanner.client)
 		}
-	} else {
-		ips, err := net.LookupIP(scanner.options.BaseDomain)
+		return nil, ctx.Err()
+	}
+	if len(targets) == 0 {
+		return nil, lastErr
+	}
+	for _, err := range errs {
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve base domain: %s", err)
+			logrus.Warnf("Skipping origin: %s", err)
 		}
-		if len(ips) == 0 {
-			return nil, fmt.Errorf("failed to resolve base domain: no A record found")
+	}
+	return targets, nil
+}
+
+// resolveTarget decides which scheme an endpoint on ip speaks if it is auto, and calibrates it. The origin is given as
+// long as it takes for the TLS probe and each baseline request to time out, so that one which accepts connections but
+// never responds cannot hold up the scan.
+func (scanner *VHOSTScanner) resolveTarget(ctx context.Context, ip net.IP, endpoint endpoint) (*vhostTarget, error) {
+
+	originCtx, cancel := context.WithTimeout(ctx, scanner.options.Timeout*time.Duration(scanner.options.Baselines+1))
+	defer cancel()
+
+	ssl := endpoint.scheme == SchemeHTTPS
+	if endpoint.scheme == SchemeAuto {
+		address := net.JoinHostPort(ip.String(), strconv.Itoa(endpoint.port))
+		var err error
+		if ssl, err = scanner.probeTLS(originCtx, address); err != nil {
+			return nil, fmt.Errorf("%s could not be connected to: %w", address, err)
 		}
-		ip = ips[0].String()
 	}
 
-	scanner.forceRequestsToIP(net.ParseIP(ip))
+	target := scanner.newTarget(ip, endpoint.port, ssl)
+	if err := scanner.calibrate(originCtx, target); err != nil {
+		target.close(scanner.client)
+		return nil, fmt.Errorf("%s could not be calibrated: %w", target.origin, err)
+	}
+	return target, nil
+}
+
+// resolveIPs returns the addresses to check vhosts against - those configured, or otherwise the base domain's
+func (scanner *VHOSTScanner) resolveIPs(ctx context.Context) ([]net.IP, error) {
+
+	inputs := scanner.options.IPs
+	if scanner.options.IP != "" {
+		inputs = append([]string{scanner.options.IP}, inputs...)
+	}
+	if len(inputs) > 0 {
+		return expandIPs(inputs)
+	}
 
-	badVHOST := fmt.Sprintf("%s.%s", md5Hash(time.Now().String()), scanner.options.BaseDomain)
+	logrus.Debug("Looking up base domain...")
 
-	url := "http://" + badVHOST
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", scanner.options.BaseDomain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base domain: %s", err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("failed to resolve base domain: no A or AAAA record found")
+	}
+	if !scanner.options.AllIPs {
+		return ips[:1], nil
+	}
+	return ips, nil
+}
 
-	if scanner.options.UseSSL {
-		url = "https://" + badVHOST
+// name describes a request for logs and errors, including the origin if there are several
+func (scanner *VHOSTScanner) name(target *vhostTarget, vhost string) string {
+	if len(scanner.targets) > 1 {
+		return vhost + " on " + target.origin
 	}
+	return vhost
+}
 
-	resp, err := scanner.client.Get(url)
+// newRequest builds a request for vhost, which the target's transport sends to its origin
+func (scanner *VHOSTScanner) newRequest(ctx context.Context, target *vhostTarget, vhost string) (*http.Request, error) {
+	scheme := "http"
+	if target.ssl {
+		scheme = "https"
+	}
+	req, err := http.NewRequestWithContext(ctx, scanner.options.Method, scheme+"://"+vhost, nil)
 	if err != nil {
 		return nil, err
 	}
-	scanner.badCode = resp.StatusCode
-	if scanner.options.ContentHashing {
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
+	for _, header := range scanner.options.Headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) == 2 && !isHostHeader(header) {
+			req.Header.Set(parts[0], strings.TrimPrefix(parts[1], " "))
+		}
+	}
+	return req, nil
+}
+
+// isHostHeader returns whether an extra header, as "Name: value", would set the Host
+func isHostHeader(header string) bool {
+	return strings.EqualFold(strings.TrimSpace(strings.SplitN(header, ":", 2)[0]), "host")
+}
+
+// reportable checks a response against the status code and length rules
+func (scanner *VHOSTScanner) reportable(result *VHOSTResult) bool {
+	if len(scanner.options.PositiveStatusCodes) > 0 && !containsInt(scanner.options.PositiveStatusCodes, result.StatusCode) &&
+		!containsInt(scanner.rules.matchedStatusCodes(), result.StatusCode) {
+		return false
+	}
+	return !containsInt(scanner.options.NegativeStatusCodes, result.StatusCode) &&
+		!containsInt(scanner.options.NegativeLengths, result.Size)
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
-		scanner.badHash = md5Hash(string(data))
-	} else {
-		io.Copy(ioutil.Discard, resp.Body)
 	}
-	resp.Body.Close()
+	return false
+}
+
+// workers returns how many worker routines to run - with adaptive concurrency, enough for the highest level allowed
+func (scanner *VHOSTScanner) workers() int {
+	if scanner.concurrency != nil {
+		return scanner.concurrency.max
+	}
+	return scanner.options.Parallelism
+}
+
+// Errors returns how many requests have failed without a response so far, by category
+func (scanner *VHOSTScanner) Errors() map[ErrorCategory]int {
+	return scanner.failures.snapshot()
+}
+
+// Concurrency returns how many requests may currently be in flight
+func (scanner *VHOSTScanner) Concurrency() int {
+	if scanner.concurrency != nil {
+		return scanner.concurrency.level()
+	}
+	return scanner.options.Parallelism
+}
+
+func md5Hash(input string) string {
+	hash := md5.New()
+	io.WriteString(hash, input)
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+func (scanner *VHOSTScanner) Scan() ([]string, error) {
+	return scanner.ScanContext(context.Background())
+}
+
+// ScanContext runs the scan until it completes or the context is cancelled. When cancelled, the results found so far
+// are returned along with the context error.
+func (scanner *VHOSTScanner) ScanContext(ctx context.Context) ([]string, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scanner.failures.start(cancel)
+
+	scanner.closeTargets()
+	targets, err := scanner.resolveTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scanner.targets = targets
+	defer scanner.closeTargets()
 
-	jobs := make(chan string, scanner.options.Parallelism)
-	results := make(chan VHOSTResult, scanner.options.Parallelism)
+	for _, target := range scanner.targets {
+		logrus.Debugf("Checking vhosts on %s", target.origin)
+	}
+
+	workers := scanner.workers()
+
+	jobs := make(chan vhostJob, workers)
+	results := make(chan VHOSTResult, workers)
 
 	wg := sync.WaitGroup{}
 
 	logrus.Debug("Starting workers...")
 
-	for i := 0; i < scanner.options.Parallelism; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
-			scanner.worker(jobs, results)
+			scanner.worker(ctx, jobs, results)
 			wg.Done()
 		}()
 	}
 
-	logrus.Debugf("Started %d workers!", scanner.options.Parallelism)
+	logrus.Debugf("Started %d workers!", workers)
 
 	logrus.Debug("Starting results gatherer...")
 
 	waitChan := make(chan struct{})
 	var foundVHOSTs []string
+	found := make(map[string]bool)
+
+	scanner.progressMutex.Lock()
+	restored := append([]VHOSTResult{}, scanner.results...)
+	scanner.progressMutex.Unlock()
+
+	// a vhost found on several IPs is returned once, but each result is sent on the result chan
+	report := func(result VHOSTResult) {
+		if scanner.options.ResultChan != nil {
+			scanner.options.ResultChan <- result
+		}
+		if !found[result.VHOST] {
+			found[result.VHOST] = true
+			foundVHOSTs = append(foundVHOSTs, result.VHOST)
+		}
+	}
 
 	go func() {
+		for _, result := range restored {
+			report(result)
+		}
 		for result := range results {
-			if scanner.options.ResultChan != nil {
-				scanner.options.ResultChan <- result
-			}
-			foundVHOSTs = append(foundVHOSTs, result.VHOST)
+			report(result)
 		}
 		if scanner.options.ResultChan != nil {
 			close(scanner.options.ResultChan)
@@ -153,20 +435,7 @@ func (scanner *VHOSTScanner) Scan() ([]string, error) {
 
 	logrus.Debug("Adding jobs...")
 
-	for {
-		if word, err := scanner.options.Wordlist.Next(); err != nil {
-			if err != io.EOF {
-				return nil, err
-			}
-			break
-		} else {
-			if word == "" {
-				continue
-			}
-			vhost := word + "." + scanner.options.BaseDomain
-			jobs <- vhost
-		}
-	}
+	loadErr := scanner.loadJobs(ctx, jobs)
 
 	close(jobs)
 
@@ -183,64 +452,224 @@ func (scanner *VHOSTScanner) Scan() ([]string, error) {
 		close(scanner.options.BusyChan)
 	}
 
+	if loadErr != nil {
+		return foundVHOSTs, loadErr
+	}
+
+	if err := scanner.failures.err(); err != nil {
+		return foundVHOSTs, err
+	}
+
 	logrus.Debug("Complete!")
 
-	return foundVHOSTs, nil
+	return foundVHOSTs, ctx.Err()
+}
+
+// loadJobs adds a job for every word in the wordlist, after any jobs left over from a resumed scan
+func (scanner *VHOSTScanner) loadJobs(ctx context.Context, jobs chan<- vhostJob) error {
+
+	for _, job := range scanner.resumedJobs {
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	scanner.resumedJobs = nil
+
+	var read int64
+	if scanner.wordPosition > 0 {
+		if seekable, ok := scanner.options.Wordlist.(wordlist.Seekable); ok {
+			if err := seekable.SeekTo(scanner.wordPosition); err != nil {
+				return err
+			}
+			read = scanner.wordPosition
+		}
+	}
+
+	for ctx.Err() == nil {
+		word, err := scanner.options.Wordlist.Next()
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		read++
+		if read <= scanner.wordPosition {
+			// already loaded before the scan was resumed
+			continue
+		}
+		var vhosts []string
+		if word != "" {
+			vhosts = append(vhosts, word+"."+scanner.options.BaseDomain)
+		}
+		scanner.stateMutex.RLock()
+		loaded := scanner.track(vhosts)
+		scanner.wordPosition = read
+		scanner.stateMutex.RUnlock()
+		for _, job := range loaded {
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// track assigns ids to new jobs and records them as pending until they have been processed. The state lock must be held.
+func (scanner *VHOSTScanner) track(vhosts []string) []vhostJob {
+	scanner.progressMutex.Lock()
+	defer scanner.progressMutex.Unlock()
+	var jobs []vhostJob
+	for _, vhost := range vhosts {
+		scanner.lastJobID++
+		scanner.pending[scanner.lastJobID] = vhost
+		jobs = append(jobs, vhostJob{id: scanner.lastJobID, vhost: vhost})
+	}
+	return jobs
 }
 
-func (scanner *VHOSTScanner) worker(jobs <-chan string, results chan<- VHOSTResult) {
+func (scanner *VHOSTScanner) worker(ctx context.Context, jobs <-chan vhostJob, results chan<- VHOSTResult) {
 	for j := range jobs {
-		if result := scanner.checkVHOST(j); result != nil {
-			results <- *result
+		if ctx.Err() != nil {
+			continue
+		}
+		found := scanner.checkVHOST(ctx, j.vhost)
+		if ctx.Err() != nil {
+			// leave the job pending, so it is checked again if the scan is resumed
+			continue
+		}
+		scanner.stateMutex.RLock()
+		scanner.progressMutex.Lock()
+		scanner.results = append(scanner.results, found...)
+		delete(scanner.pending, j.id)
+		scanner.progressMutex.Unlock()
+		scanner.stateMutex.RUnlock()
+		for _, result := range found {
+			results <- result
 		}
 	}
 }
 
-// hit a url - is it one of certain response codes? leave connections open!
-func (scanner *VHOSTScanner) checkVHOST(vhost string) *VHOSTResult {
+// checkVHOST checks a vhost against every target, returning a result for each it exists on
+func (scanner *VHOSTScanner) checkVHOST(ctx context.Context, vhost string) []VHOSTResult {
 
 	if scanner.options.BusyChan != nil {
 		scanner.options.BusyChan <- vhost
 	}
 
-	var code int
-	var hash string
+	var found []VHOSTResult
+	for _, target := range scanner.targets {
+		if result := scanner.checkTarget(ctx, target, vhost); result != nil {
+			found = append(found, *result)
+		}
+	}
+	return found
+}
 
-	url := "http://" + vhost
+// hit a url - is it one of certain response codes? leave connections open!
+func (scanner *VHOSTScanner) checkTarget(ctx context.Context, target *vhostTarget, vhost string) *VHOSTResult {
 
-	if scanner.options.UseSSL {
-		url = "https://" + vhost
-	}
+	name := scanner.name(target, vhost)
+
+	var result *VHOSTResult
+	var response vhostResponse
+	var accepted bool
+	var failed error // set if the last attempt got no response, or was throttled
 
-	if err := retry.Do(func() error {
-		resp, err := scanner.client.Get(url)
+	retries, err := scanner.options.RetryPolicy.do(ctx, name, func(last bool) error {
+		failed = nil
+
+		req, err := scanner.newRequest(ctx, target, vhost)
 		if err != nil {
-			return nil
+			return err
 		}
 
-		if scanner.options.ContentHashing {
-			data, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				return err
-			}
-			hash = md5Hash(string(data))
-		} else {
-			io.Copy(ioutil.Discard, resp.Body)
+		if err := scanner.concurrency.acquire(ctx); err != nil {
+			return err
 		}
-		resp.Body.Close()
 
-		code = resp.StatusCode
-		return nil
-	}, retry.Attempts(10), retry.DelayType(retry.BackOffDelay)); err != nil {
+		if err := scanner.limiter.wait(ctx, target.ip); err != nil {
+			scanner.concurrency.release(0, err)
+			return err
+		}
+
+		started := time.Now()
+		resp, err := target.client.Do(req)
+		if err != nil {
+			scanner.concurrency.release(0, err)
+			failed = err
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if err := scanner.limiter.observe(target.ip, resp); err != nil {
+			scanner.concurrency.release(0, err)
+			logrus.Debug(err)
+			failed = err
+			return err
+		}
+		scanner.concurrency.release(time.Since(started), nil)
+		scanner.failures.succeed()
+
+		if !last && scanner.options.RetryPolicy.retryStatus(resp.StatusCode) {
+			return fmt.Errorf("%w: %s responded with %d", errServerError, name, resp.StatusCode)
+		}
+
+		body, size, err := readBody(resp)
+		if err != nil {
+			failed = err
+			return err
+		}
+
+		response = newVHOSTResponse(vhost, resp, body, size)
+		result = &VHOSTResult{
+			StatusCode:       resp.StatusCode,
+			VHOST:            vhost,
+			IP:               target.ip,
+			Origin:           target.origin,
+			Size:             size,
+			ResponseMetadata: newResponseMetadata(resp, body, time.Since(started)),
+		}
+		accepted = scanner.rules.accept(&Response{
+			StatusCode:       result.StatusCode,
+			Size:             size,
+			Header:           resp.Header,
+			Body:             body,
+			ResponseMetadata: result.ResponseMetadata,
+		})
 		return nil
+	})
+
+	if retries > 0 {
+		logrus.Debugf("Checked %s with %d retries", name, retries)
 	}
 
-	if code != scanner.badCode || (scanner.options.ContentHashing && hash != scanner.badHash) {
-		return &VHOSTResult{
-			StatusCode: code,
-			VHOST:      vhost,
+	if err != nil {
+		if failed != nil && ctx.Err() == nil {
+			scanner.failures.fail(name, failed)
 		}
+		return nil
 	}
 
-	return nil
+	if ctx.Err() != nil || result == nil || !accepted || !scanner.reportable(result) {
+		return nil
+	}
+
+	if target.matchingBaseline(response, scanner.options.Similarity, scanner.options.ContentHashing) != nil {
+		return nil
+	}
+
+	return result
+}
+
+// Results returns every result found so far, with one for each IP a vhost was found on
+func (scanner *VHOSTScanner) Results() []VHOSTResult {
+	scanner.progressMutex.Lock()
+	defer scanner.progressMutex.Unlock()
+	return append([]VHOSTResult{}, scanner.results...)
 }
@@ -1,12 +1,24 @@
 package scan
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/liamg/scout/pkg/wordlist"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -54,3 +66,595 @@ func TestVHOSTScanner(t *testing.T) {
 	assert.Equal(t, results[0], "admin.site.eg")
 
 }
+
+func TestVHOSTScannerWithRateLimit(t *testing.T) {
+
+	var throttled int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "admin.site.eg" && atomic.CompareAndSwapInt32(&throttled, 0, 1) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.Host {
+		case "admin.site.eg":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	events := make(chan ThrottleEvent, 1)
+	scanner := NewVHOSTScanner(&VHOSTOptions{
+		BaseDomain:   "site.eg",
+		IP:           parsed.Hostname(),
+		Port:         port,
+		Parallelism:  2,
+		Wordlist:     wordlist.FromReader(strings.NewReader("www\nadmin\nmail\nftp")),
+		RateLimit:    RateLimit{RequestsPerSecond: 20},
+		ThrottleChan: events,
+	})
+
+	started := time.Now()
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admin.site.eg"}, results)
+	// the 503 pauses the scan for a second, on top of the requests being spaced out
+	assert.True(t, time.Since(started) >= time.Second, "took %s", time.Since(started))
+	assert.Equal(t, http.StatusServiceUnavailable, (<-events).StatusCode)
+}
+
+func TestVHOSTScannerErrorBudget(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "word") {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	var words []string
+	for i := 0; i < 100; i++ {
+		words = append(words, "word"+strconv.Itoa(i))
+	}
+
+	errorChan := make(chan ScanError, 100)
+	scanner := NewVHOSTScanner(&VHOSTOptions{
+		BaseDomain:  "site.eg",
+		IP:          parsed.Hostname(),
+		Port:        port,
+		Parallelism: 1,
+		Wordlist:    wordlist.FromReader(strings.NewReader(strings.Join(words, "\n"))),
+		ErrorChan:   errorChan,
+		ErrorBudget: 5,
+		RetryPolicy: RetryPolicy{Attempts: 1},
+	})
+
+	_, err = scanner.Scan()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrTargetUnreachable), err.Error())
+	assert.Equal(t, map[ErrorCategory]int{ErrorReset: 5}, scanner.Errors())
+
+	require.Equal(t, 5, len(errorChan))
+	failed := <-errorChan
+	assert.Equal(t, "word0.site.eg", failed.URL)
+	assert.Equal(t, ErrorReset, failed.Category)
+}
+
+func TestVHOSTScannerWithOptions(t *testing.T) {
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.Header.Get("X-Scout") != "yes" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Host {
+		case "admin.site.eg":
+			w.WriteHeader(http.StatusOK)
+		case "secret.site.eg":
+			w.WriteHeader(http.StatusForbidden)
+		case "dev.site.eg":
+			w.Header().Set("Content-Length", "7")
+			w.WriteHeader(http.StatusOK)
+		case "staging.site.eg":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	scanner := NewVHOSTScanner(
+		WithVHOSTBaseDomain("site.eg"),
+		WithVHOSTIP(parsed.Hostname()),
+		WithVHOSTPort(port),
+		WithVHOSTParallelism(1),
+		WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\nsecret\ndev\nstaging\nnothing\n"))),
+		WithVHOSTMethod(http.MethodHead),
+		WithVHOSTHeaders([]string{"X-Scout: yes", "Host: ignored.eg"}),
+		WithVHOSTPositiveStatusCodes([]int{200, 401, 403}),
+		WithVHOSTNegativeStatusCodes([]int{401}),
+		WithVHOSTNegativeLengths([]int{7}),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"admin.site.eg", "secret.site.eg"}, results)
+
+	// the Host is what is being guessed, so it cannot be overridden
+	var warned bool
+	for _, entry := range hook.AllEntries() {
+		warned = warned || (entry.Level == logrus.WarnLevel && strings.Contains(entry.Message, "Host: ignored.eg"))
+	}
+	assert.True(t, warned)
+}
+
+func TestVHOSTScannerWithCancellation(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.Host, "found"):
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.Host, "slow"):
+			time.Sleep(time.Millisecond * 50)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	var words []string
+	for i := 0; i < 1000; i++ {
+		words = append(words, fmt.Sprintf("found%d", i), fmt.Sprintf("slow%d", i))
+	}
+
+	goroutines := runtime.NumGoroutine()
+
+	resultChan := make(chan VHOSTResult)
+	var received int32
+	consumed := make(chan struct{})
+	go func() {
+		for range resultChan {
+			atomic.AddInt32(&received, 1)
+		}
+		close(consumed)
+	}()
+
+	scanner := NewVHOSTScanner(
+		WithVHOSTBaseDomain("site.eg"),
+		WithVHOSTIP("127.0.0.1"),
+		WithVHOSTPort(port),
+		WithVHOSTParallelism(4),
+		WithVHOSTResultChan(resultChan),
+		WithVHOSTWordlist(wordlist.FromReader(strings.NewReader(strings.Join(words, "\n")))),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(time.Millisecond*300, cancel)
+
+	started := time.Now()
+	results, err := scanner.ScanContext(ctx)
+	require.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(started) < time.Second*2, time.Since(started).String())
+	assert.True(t, len(results) > 0)
+	assert.True(t, len(results) < len(words)/2)
+
+	// the result chan is closed before the scan returns, so nothing can be sent on it afterwards
+	select {
+	case <-consumed:
+	case <-time.After(time.Second):
+		t.Fatal("result chan was not closed when the scan returned")
+	}
+	assert.Equal(t, int32(len(results)), atomic.LoadInt32(&received))
+
+	// every worker and the gatherer have exited, leaving only connections which are closing
+	server.CloseClientConnections()
+	deadline := time.Now().Add(time.Second * 2)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.True(t, runtime.NumGoroutine() <= goroutines, "%d goroutines, from %d", runtime.NumGoroutine(), goroutines)
+}
+
+func TestVHOSTScannerOptionsStruct(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "admin.site.eg" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	// options after the struct are applied on top of it
+	scanner := NewVHOSTScanner(&VHOSTOptions{
+		BaseDomain: "site.eg",
+		IP:         parsed.Hostname(),
+		Port:       port + 1,
+	}, WithVHOSTPort(port), WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\n"))))
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin.site.eg"}, results)
+
+	// the defaults are not changed by creating a scanner without options
+	NewVHOSTScanner(nil)
+	assert.Nil(t, DefaultVHOSTOptions.Wordlist)
+}
+
+func TestVHOSTScannerThroughProxy(t *testing.T) {
+
+	var tunnels int32
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "admin.site.eg" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer target.Close()
+
+	targetURL, err := url.Parse(target.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(targetURL.Port())
+	require.NoError(t, err)
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Host != targetURL.Host {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+		atomic.AddInt32(&tunnels, 1)
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	scanner := NewVHOSTScanner(
+		WithVHOSTBaseDomain("site.eg"),
+		WithVHOSTIP("127.0.0.1"),
+		WithVHOSTPort(port),
+		WithVHOSTProxy(proxyURL),
+		WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\nnothing\n"))),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin.site.eg"}, results)
+	assert.True(t, atomic.LoadInt32(&tunnels) > 0)
+}
+
+func TestVHOSTScannerIgnoresEnvironmentProxy(t *testing.T) {
+
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("HTTPS_PROXY", proxy.URL)
+
+	for _, ssl := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ssl=%t", ssl), func(t *testing.T) {
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Host != "admin.site.eg" {
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+			server := httptest.NewServer(handler)
+			if ssl {
+				server.Close()
+				server = httptest.NewTLSServer(handler)
+			}
+			defer server.Close()
+
+			parsed, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			port, err := strconv.Atoi(parsed.Port())
+			require.NoError(t, err)
+
+			scanner := NewVHOSTScanner(
+				WithVHOSTBaseDomain("site.eg"),
+				WithVHOSTIP("127.0.0.1"),
+				WithVHOSTPort(port),
+				WithVHOSTSSL(ssl),
+				WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\nnothing\n"))),
+			)
+
+			results, err := scanner.Scan()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"admin.site.eg"}, results)
+
+			// the environment is only read once per process, so the transports are checked as well as the requests
+			for _, target := range scanner.targets {
+				assert.Nil(t, target.client.Transport.(*http.Transport).Proxy)
+			}
+		})
+	}
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&proxied))
+}
+
+func TestVHOSTScannerWithDynamicCatchAll(t *testing.T) {
+
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if r.Host == "admin.site.eg" {
+			_, _ = w.Write([]byte("<html><title>Admin</title><body>Sign in to the admin dashboard with your username and password</body></html>"))
+			return
+		}
+		// every other vhost gets the same page, with a token, greeting and timestamp which change on every request
+		_, _ = fmt.Fprintf(w, `<html><title>Default</title><body><h1>It works!</h1>
+<p>This is the default page for this server, which is shown for any site which has not been configured.</p>
+<input type="hidden" name="token" value="%x"><p>Good %s, generated at %s</p></body></html>`,
+			md5Hash(strconv.Itoa(int(n))), []string{"morning", "afternoon", "evening"}[n%3], strings.Repeat("9", int(n%4)+1))
+	}))
+	defer server.Close()
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	scanner := NewVHOSTScanner(
+		WithVHOSTBaseDomain("site.eg"),
+		WithVHOSTIP(parsed.Hostname()),
+		WithVHOSTPort(port),
+		WithVHOSTContentHashing(true),
+		WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("www\nadmin\nmail\nftp\ndev\n"))),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin.site.eg"}, results)
+	require.Equal(t, 1, len(scanner.targets[0].baselines))
+	assert.Equal(t, DefaultVHOSTOptions.Baselines, len(scanner.targets[0].baselines[0].simhashes))
+}
+
+func TestVHOSTScannerWithMultipleIPs(t *testing.T) {
+
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "admin.site.eg" && r.Host != "dev.site.eg" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer first.Close()
+
+	parsed, err := url.Parse(first.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+
+	// the second node of the pool does not have the dev vhost
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.2", parsed.Port()))
+	if err != nil {
+		t.Skipf("cannot listen on a second loopback address: %s", err)
+	}
+	second := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Host != "admin.site.eg" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})},
+	}
+	second.Start()
+	defer second.Close()
+
+	scanner := NewVHOSTScanner(
+		WithVHOSTBaseDomain("site.eg"),
+		WithVHOSTIPs("127.0.0.1", "127.0.0.2/32"),
+		WithVHOSTPort(port),
+		WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\ndev\nnothing\n"))),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"admin.site.eg", "dev.site.eg"}, results)
+
+	var found []string
+	for _, result := range scanner.Results() {
+		found = append(found, result.VHOST+" on "+result.IP)
+	}
+	assert.ElementsMatch(t, []string{"admin.site.eg on 127.0.0.1", "admin.site.eg on 127.0.0.2", "dev.site.eg on 127.0.0.1"}, found)
+}
+
+func TestVHOSTScannerWithIPv6(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %s", err)
+	}
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Host != "admin.site.eg" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})},
+	}
+	server.Start()
+	defer server.Close()
+
+	scanner := NewVHOSTScanner(
+		WithVHOSTBaseDomain("site.eg"),
+		WithVHOSTIP("::1"),
+		WithVHOSTPort(listener.Addr().(*net.TCPAddr).Port),
+		WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\nnothing\n"))),
+	)
+
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin.site.eg"}, results)
+	assert.Equal(t, "::1", scanner.Results()[0].IP)
+}
+
+func TestVHOSTScannerWithPortsAndSchemes(t *testing.T) {
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "admin.site.eg" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	var ports []int
+	var origins []string
+	for _, server := range []*httptest.Server{plain, secure} {
+		parsed, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(parsed.Port())
+		require.NoError(t, err)
+		ports = append(ports, port)
+		origins = append(origins, server.URL)
+	}
+
+	// a closed port is skipped rather than failing the scan
+	closed := httptest.NewServer(handler)
+	closedURL, err := url.Parse(closed.URL)
+	require.NoError(t, err)
+	closedPort, err := strconv.Atoi(closedURL.Port())
+	require.NoError(t, err)
+	closed.Close()
+
+	for _, scheme := range []Scheme{SchemeAuto, SchemeBoth} {
+		t.Run(string(scheme), func(t *testing.T) {
+			scanner := NewVHOSTScanner(
+				WithVHOSTBaseDomain("site.eg"),
+				WithVHOSTIP("127.0.0.1"),
+				WithVHOSTPorts(append(ports, closedPort)...),
+				WithVHOSTScheme(scheme),
+				WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\nnothing\n"))),
+			)
+
+			results, err := scanner.Scan()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"admin.site.eg"}, results)
+
+			var found []string
+			for _, result := range scanner.Results() {
+				found = append(found, result.Origin)
+			}
+			assert.ElementsMatch(t, origins, found)
+		})
+	}
+}
+
+func TestVHOSTScannerSkipsUnresponsiveOrigins(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "admin.site.eg" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(parsed.Port())
+	require.NoError(t, err)
+	ports := []int{port}
+
+	// these accept connections but never respond, so each can only be skipped once its requests time out
+	for i := 0; i < 5; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer func() { _ = listener.Close() }()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+			}
+		}()
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	scanner := NewVHOSTScanner(
+		WithVHOSTBaseDomain("site.eg"),
+		WithVHOSTIP("127.0.0.1"),
+		WithVHOSTPorts(ports...),
+		WithVHOSTTimeout(time.Millisecond*500),
+		WithVHOSTWordlist(wordlist.FromReader(strings.NewReader("admin\nnothing\n"))),
+	)
+
+	started := time.Now()
+	results, err := scanner.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin.site.eg"}, results)
+
+	// the origins are calibrated at once, rather than waiting for each to time out in turn
+	assert.True(t, time.Since(started) < time.Second*2, time.Since(started).String())
+
+	var skipped int
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel && strings.HasPrefix(entry.Message, "Skipping origin") {
+			skipped++
+		}
+	}
+	assert.Equal(t, 5, skipped)
+}
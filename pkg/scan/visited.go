@@ -0,0 +1,86 @@
+package scan
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// VisitedSet records which requests a scan has made, so that each is only made once. The scanner serialises calls, so
+// implementations need not be safe for concurrent use.
+type VisitedSet interface {
+	// Visit marks key as visited, returning true if it already was
+	Visit(key string) bool
+}
+
+// mapVisitedSet is exact, but holds every key in memory
+type mapVisitedSet map[string]struct{}
+
+// NewMapVisitedSet creates an exact visited set held in memory. This is the default.
+func NewMapVisitedSet() VisitedSet {
+	return make(mapVisitedSet)
+}
+
+func (m mapVisitedSet) Visit(key string) bool {
+	if _, ok := m[key]; ok {
+		return true
+	}
+	m[key] = struct{}{}
+	return false
+}
+
+// bloomVisitedSet uses a fixed amount of memory whatever the size of the scan, at the cost of occasionally believing a
+// request was made when it was not - so that request is skipped
+type bloomVisitedSet struct {
+	bits   []uint64
+	size   uint64 // number of bits
+	hashes uint64 // number of bits set for each key
+}
+
+// NewBloomVisitedSet creates a visited set backed by a bloom filter sized for the expected number of requests. Once
+// that many have been made, roughly falsePositiveRate of the remaining requests will be skipped as though they had
+// already been made. Beyond it, the rate climbs.
+//
+// The filter cannot list the requests it holds, so none are saved with the state of a scan. When a scan using
+// WithVisitedJournal is resumed, the journal is replayed into a new filter, which ends up with the same bits as the old
+// one as long as it has the same capacity and rate. Without a journal, a resumed scan starts with an empty filter, and
+// may make requests again, although it does not report the results already found again.
+func NewBloomVisitedSet(capacity int, falsePositiveRate float64) VisitedSet {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+	size := math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(size / float64(capacity) * math.Ln2)
+	if hashes < 1 {
+		hashes = 1
+	}
+	words := (uint64(size) + 63) / 64
+	return &bloomVisitedSet{
+		bits:   make([]uint64, words),
+		size:   words * 64,
+		hashes: uint64(hashes),
+	}
+}
+
+func (b *bloomVisitedSet) Visit(key string) bool {
+	// two independent hashes are combined to give as many as are needed
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1
+
+	visited := true
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			visited = false
+			b.bits[word] |= mask
+		}
+	}
+	return visited
+}
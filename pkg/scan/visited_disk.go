@@ -0,0 +1,204 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	diskSlotSize     = 16 // hash and offset of the key in the log
+	diskInitialSlots = 1 << 16
+)
+
+// DiskVisitedSet is an exact visited set which keeps its keys in temporary files rather than in memory. Keys are
+// appended to a log, and found again through an open addressing hash table of their hashes and offsets in the log.
+type DiskVisitedSet struct {
+	dir   string
+	keys  *os.File
+	table *os.File
+	end   int64 // length of the key log
+	slots uint64
+	count uint64
+}
+
+// NewDiskVisitedSet creates a visited set in a new temporary directory within dir, or within the system temporary
+// directory if dir is empty. Close removes it.
+func NewDiskVisitedSet(dir string) (*DiskVisitedSet, error) {
+	dir, err := ioutil.TempDir(dir, "scout-visited-")
+	if err != nil {
+		return nil, err
+	}
+	set := &DiskVisitedSet{dir: dir}
+	if set.keys, err = os.Create(filepath.Join(dir, "keys")); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	if set.table, err = set.createTable(diskInitialSlots); err != nil {
+		_ = set.Close()
+		return nil, err
+	}
+	set.slots = diskInitialSlots
+	return set, nil
+}
+
+func (d *DiskVisitedSet) createTable(slots uint64) (*os.File, error) {
+	table, err := ioutil.TempFile(d.dir, "table-")
+	if err != nil {
+		return nil, err
+	}
+	// the file is sparse, and empty slots read as zero
+	if err := table.Truncate(int64(slots * diskSlotSize)); err != nil {
+		_ = table.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+// Visit marks key as visited, returning true if it already was. If the files cannot be read or written, the key is
+// treated as not visited, so that nothing is missed.
+func (d *DiskVisitedSet) Visit(key string) bool {
+	visited, err := d.visit(key)
+	if err != nil {
+		logrus.Debugf("Visited set error: %s", err)
+		return false
+	}
+	return visited
+}
+
+func (d *DiskVisitedSet) visit(key string) (bool, error) {
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	hash := h.Sum64()
+	if hash == 0 {
+		// zero marks an empty slot
+		hash = 1
+	}
+
+	for slot := hash % d.slots; ; slot = (slot + 1) % d.slots {
+		slotHash, offset, err := d.readSlot(d.table, slot)
+		if err != nil {
+			return false, err
+		}
+		if slotHash == 0 {
+			break
+		}
+		if slotHash != hash {
+			continue
+		}
+		existing, err := d.readKey(offset)
+		if err != nil {
+			return false, err
+		}
+		if existing == key {
+			return true, nil
+		}
+	}
+
+	offset, err := d.appendKey(key)
+	if err != nil {
+		return false, err
+	}
+	if err := d.insert(d.table, d.slots, hash, offset); err != nil {
+		return false, err
+	}
+	d.count++
+	if d.count*2 >= d.slots {
+		return false, d.grow()
+	}
+	return false, nil
+}
+
+func (d *DiskVisitedSet) readSlot(table *os.File, slot uint64) (uint64, uint64, error) {
+	buffer := make([]byte, diskSlotSize)
+	if _, err := table.ReadAt(buffer, int64(slot*diskSlotSize)); err != nil {
+		return 0, 0, err
+	}
+	return binary.LittleEndian.Uint64(buffer[:8]), binary.LittleEndian.Uint64(buffer[8:]), nil
+}
+
+// insert writes a hash and offset to the first empty slot for it
+func (d *DiskVisitedSet) insert(table *os.File, slots uint64, hash uint64, offset uint64) error {
+	for slot := hash % slots; ; slot = (slot + 1) % slots {
+		existing, _, err := d.readSlot(table, slot)
+		if err != nil {
+			return err
+		}
+		if existing != 0 {
+			continue
+		}
+		buffer := make([]byte, diskSlotSize)
+		binary.LittleEndian.PutUint64(buffer[:8], hash)
+		binary.LittleEndian.PutUint64(buffer[8:], offset)
+		_, err = table.WriteAt(buffer, int64(slot*diskSlotSize))
+		return err
+	}
+}
+
+func (d *DiskVisitedSet) readKey(offset uint64) (string, error) {
+	length := make([]byte, 4)
+	if _, err := d.keys.ReadAt(length, int64(offset)); err != nil {
+		return "", err
+	}
+	key := make([]byte, binary.LittleEndian.Uint32(length))
+	if _, err := d.keys.ReadAt(key, int64(offset)+4); err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+func (d *DiskVisitedSet) appendKey(key string) (uint64, error) {
+	entry := make([]byte, 4+len(key))
+	binary.LittleEndian.PutUint32(entry, uint32(len(key)))
+	copy(entry[4:], key)
+	offset := d.end
+	if _, err := d.keys.WriteAt(entry, offset); err != nil {
+		return 0, err
+	}
+	d.end += int64(len(entry))
+	return uint64(offset), nil
+}
+
+// grow doubles the size of the hash table, so that probing stays short
+func (d *DiskVisitedSet) grow() error {
+	slots := d.slots * 2
+	table, err := d.createTable(slots)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(io.NewSectionReader(d.table, 0, int64(d.slots*diskSlotSize)))
+	buffer := make([]byte, diskSlotSize)
+	for slot := uint64(0); slot < d.slots; slot++ {
+		_, err := io.ReadFull(reader, buffer)
+		if hash := binary.LittleEndian.Uint64(buffer[:8]); err == nil && hash != 0 {
+			err = d.insert(table, slots, hash, binary.LittleEndian.Uint64(buffer[8:]))
+		}
+		if err != nil {
+			_ = table.Close()
+			_ = os.Remove(table.Name())
+			return err
+		}
+	}
+	old := d.table
+	d.table, d.slots = table, slots
+	_ = old.Close()
+	return os.Remove(old.Name())
+}
+
+// Close removes the files holding the set
+func (d *DiskVisitedSet) Close() error {
+	if d.keys != nil {
+		_ = d.keys.Close()
+	}
+	if d.table != nil {
+		_ = d.table.Close()
+	}
+	return os.RemoveAll(d.dir)
+}
@@ -0,0 +1,97 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// VisitedJournal is a file which a URL scan appends each request it makes to, so that the state of the scan need only
+// record how much of the journal had been written rather than every request. When the scan is resumed, the journal is
+// replayed into the visited set, whichever kind it is.
+type VisitedJournal struct {
+	file   *os.File
+	writer *bufio.Writer
+	size   int64 // bytes written, including any still buffered
+	saved  int64 // bytes written when the journal was last flushed
+	err    error // the first write error, after which nothing more is written
+}
+
+// OpenVisitedJournal opens the journal at path, creating it if it does not exist. A scan which is not resumed starts
+// it again from empty.
+func OpenVisitedJournal(path string) (*VisitedJournal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &VisitedJournal{
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+// replay calls fn with each request in the first size bytes of the journal, and discards the rest, which was written
+// after the state being resumed was saved
+func (j *VisitedJournal) replay(size int64, fn func(key string)) error {
+
+	reader := bufio.NewReader(io.NewSectionReader(j.file, 0, size))
+	length := make([]byte, 4)
+	var read int64
+	for {
+		// a short read is the end of the journal, or an entry which was only partly written
+		if _, err := io.ReadFull(reader, length); err != nil {
+			break
+		}
+		key := make([]byte, binary.LittleEndian.Uint32(length))
+		if _, err := io.ReadFull(reader, key); err != nil {
+			break
+		}
+		fn(string(key))
+		read += int64(len(length) + len(key))
+	}
+
+	if err := j.file.Truncate(read); err != nil {
+		return err
+	}
+	j.writer.Reset(j.file)
+	j.size, j.saved = read, read
+	return nil
+}
+
+// append records a request. It is buffered until the next checkpoint.
+func (j *VisitedJournal) append(key string) {
+	if j.err != nil {
+		return
+	}
+	entry := make([]byte, 4+len(key))
+	binary.LittleEndian.PutUint32(entry, uint32(len(key)))
+	copy(entry[4:], key)
+	if _, err := j.writer.Write(entry); err != nil {
+		j.err = err
+		return
+	}
+	j.size += int64(len(entry))
+}
+
+// checkpoint flushes the journal, returning how much of it has been written. If it could not all be written, the size
+// up to the last successful checkpoint is returned with the error, so that later requests are made again on resume.
+func (j *VisitedJournal) checkpoint() (int64, error) {
+	if j.err == nil {
+		if err := j.writer.Flush(); err != nil {
+			j.err = err
+		} else {
+			j.saved = j.size
+		}
+	}
+	return j.saved, j.err
+}
+
+// Close flushes and closes the journal
+func (j *VisitedJournal) Close() error {
+	_, err := j.checkpoint()
+	if closeErr := j.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisitedSets(t *testing.T) {
+
+	disk, err := NewDiskVisitedSet(t.TempDir())
+	require.NoError(t, err)
+	defer func() { _ = disk.Close() }()
+
+	sets := map[string]VisitedSet{
+		"map":   NewMapVisitedSet(),
+		"bloom": NewBloomVisitedSet(100000, 0.001),
+		"disk":  disk,
+	}
+
+	for name, set := range sets {
+		t.Run(name, func(t *testing.T) {
+			// enough keys to make the disk set grow its table several times
+			var repeated int
+			for i := 0; i < 100000; i++ {
+				if set.Visit(fmt.Sprintf("https://example.com/%d", i)) {
+					repeated++
+				}
+			}
+			if name == "bloom" {
+				// false positives are expected, but should be rare
+				assert.True(t, repeated < 1000, "%d false positives", repeated)
+			} else {
+				assert.Equal(t, 0, repeated)
+			}
+			for i := 0; i < 100000; i++ {
+				if !set.Visit(fmt.Sprintf("https://example.com/%d", i)) {
+					t.Fatalf("https://example.com/%d was not visited", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBloomVisitedSetFalsePositiveRate(t *testing.T) {
+
+	// checking a key also adds it, so the filter is sized for both halves of the test
+	set := NewBloomVisitedSet(20000, 0.01)
+	for i := 0; i < 10000; i++ {
+		set.Visit(fmt.Sprintf("/visited/%d", i))
+	}
+
+	var falsePositives int
+	for i := 0; i < 10000; i++ {
+		if set.Visit(fmt.Sprintf("/unvisited/%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.True(t, falsePositives < 200, "%d false positives", falsePositives)
+}
+
+func TestDiskVisitedSetGrows(t *testing.T) {
+
+	set, err := NewDiskVisitedSet(t.TempDir())
+	require.NoError(t, err)
+
+	for i := 0; i < 50000; i++ {
+		require.False(t, set.Visit(fmt.Sprintf("/%d", i)))
+	}
+	for i := 0; i < 50000; i++ {
+		require.True(t, set.Visit(fmt.Sprintf("/%d", i)))
+	}
+
+	require.NoError(t, set.Close())
+	_, err = os.Stat(set.dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestVisitedJournal(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "visited")
+
+	journal, err := OpenVisitedJournal(path)
+	require.NoError(t, err)
+	require.NoError(t, journal.replay(0, func(string) {}))
+	journal.append("/a")
+	journal.append("/b")
+	size, err := journal.checkpoint()
+	require.NoError(t, err)
+	// written after the state was saved, so must not be replayed
+	journal.append("/c")
+	require.NoError(t, journal.Close())
+
+	journal, err = OpenVisitedJournal(path)
+	require.NoError(t, err)
+	var replayed []string
+	require.NoError(t, journal.replay(size, func(key string) {
+		replayed = append(replayed, key)
+	}))
+	assert.Equal(t, []string{"/a", "/b"}, replayed)
+
+	journal.append("/d")
+	require.NoError(t, journal.Close())
+
+	journal, err = OpenVisitedJournal(path)
+	require.NoError(t, err)
+	replayed = nil
+	require.NoError(t, journal.replay(size+6, func(key string) {
+		replayed = append(replayed, key)
+	}))
+	assert.Equal(t, []string{"/a", "/b", "/d"}, replayed)
+	require.NoError(t, journal.Close())
+}
@@ -0,0 +1,200 @@
+package wordlist
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeywordList binds a wordlist to the keyword its words replace
+type KeywordList struct {
+	Keyword  string
+	Wordlist Wordlist
+}
+
+// Combination is a Wordlist which combines several keyword-bound wordlists. Each call to Next moves on to the next
+// combination of words, which can then be read with Values. Next returns the words joined with commas.
+type Combination interface {
+	Seekable
+	Keywords() []string
+	Values() map[string]string // the word for each keyword in the current combination
+}
+
+type combination struct {
+	lists    []KeywordList
+	current  []string
+	position int64
+}
+
+func (c *combination) Keywords() []string {
+	var keywords []string
+	for _, list := range c.lists {
+		keywords = append(keywords, list.Keyword)
+	}
+	return keywords
+}
+
+func (c *combination) Values() map[string]string {
+	values := make(map[string]string)
+	for i, word := range c.current {
+		values[c.lists[i].Keyword] = word
+	}
+	return values
+}
+
+func (c *combination) Position() int64 {
+	return c.position
+}
+
+func (c *combination) String() string {
+	return strings.Join(c.current, ",")
+}
+
+// seekTo skips forward with next until the given number of combinations have been read
+func (c *combination) seekTo(position int64, next func() (string, error)) error {
+	if position < c.position {
+		return fmt.Errorf("cannot seek backwards from combination %d to combination %d", c.position, position)
+	}
+	for c.position < position {
+		if _, err := next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextWord returns the next non-empty word from a wordlist
+func nextWord(list Wordlist) (string, error) {
+	for {
+		word, err := list.Next()
+		if err != nil {
+			return "", err
+		}
+		if word != "" {
+			return word, nil
+		}
+	}
+}
+
+type clusterBomb struct {
+	combination
+	cached  [][]string // the words of every list but the first, which have to be replayed for each word of the first
+	indices []int
+	started bool
+	done    bool
+}
+
+// ClusterBomb combines every word of each list with every word of the others. The first list is streamed, while the
+// others are read into memory.
+func ClusterBomb(lists ...KeywordList) Combination {
+	return &clusterBomb{combination: combination{lists: lists}}
+}
+
+func (cb *clusterBomb) Next() (string, error) {
+	if cb.done {
+		return "", io.EOF
+	}
+	word, err := cb.next()
+	if err == io.EOF {
+		cb.done = true
+	}
+	return word, err
+}
+
+func (cb *clusterBomb) next() (string, error) {
+
+	if len(cb.lists) == 0 {
+		return "", io.EOF
+	}
+
+	if !cb.started {
+		cb.started = true
+		cb.current = make([]string, len(cb.lists))
+		cb.indices = make([]int, len(cb.lists))
+		cb.cached = make([][]string, len(cb.lists))
+		for i := 1; i < len(cb.lists); i++ {
+			for {
+				word, err := nextWord(cb.lists[i].Wordlist)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return "", err
+				}
+				cb.cached[i] = append(cb.cached[i], word)
+			}
+			if len(cb.cached[i]) == 0 {
+				return "", io.EOF
+			}
+		}
+		if err := cb.advanceFirst(); err != nil {
+			return "", err
+		}
+	} else {
+		// move on like an odometer, with the last list changing fastest
+		i := len(cb.lists) - 1
+		for ; i > 0; i-- {
+			cb.indices[i]++
+			if cb.indices[i] < len(cb.cached[i]) {
+				break
+			}
+			cb.indices[i] = 0
+		}
+		if i == 0 {
+			if err := cb.advanceFirst(); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	for i := 1; i < len(cb.lists); i++ {
+		cb.current[i] = cb.cached[i][cb.indices[i]]
+	}
+	cb.position++
+	return cb.String(), nil
+}
+
+func (cb *clusterBomb) advanceFirst() error {
+	word, err := nextWord(cb.lists[0].Wordlist)
+	if err != nil {
+		return err
+	}
+	cb.current[0] = word
+	return nil
+}
+
+// SeekTo skips forward until the given number of combinations have been read
+func (cb *clusterBomb) SeekTo(position int64) error {
+	return cb.seekTo(position, cb.Next)
+}
+
+type pitchfork struct {
+	combination
+}
+
+// Pitchfork combines the nth word of each list, stopping when the shortest list runs out
+func Pitchfork(lists ...KeywordList) Combination {
+	return &pitchfork{combination: combination{lists: lists}}
+}
+
+func (pf *pitchfork) Next() (string, error) {
+	if len(pf.lists) == 0 {
+		return "", io.EOF
+	}
+	current := make([]string, len(pf.lists))
+	for i, list := range pf.lists {
+		word, err := nextWord(list.Wordlist)
+		if err != nil {
+			return "", err
+		}
+		current[i] = word
+	}
+	pf.current = current
+	pf.position++
+	return pf.String(), nil
+}
+
+// SeekTo skips forward until the given number of combinations have been read
+func (pf *pitchfork) SeekTo(position int64) error {
+	return pf.seekTo(position, pf.Next)
+}
@@ -0,0 +1,71 @@
+package wordlist
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func keywordList(keyword string, words string) KeywordList {
+	return KeywordList{Keyword: keyword, Wordlist: FromReader(bytes.NewReader([]byte(words)))}
+}
+
+func readAll(t *testing.T, combination Combination) []map[string]string {
+	var all []map[string]string
+	for {
+		_, err := combination.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		all = append(all, combination.Values())
+	}
+	return all
+}
+
+func TestClusterBomb(t *testing.T) {
+	combination := ClusterBomb(keywordList("USER", "alice\nbob"), keywordList("PASS", "1\n\n2\n3"))
+
+	assert.Equal(t, []string{"USER", "PASS"}, combination.Keywords())
+	assert.Equal(t, []map[string]string{
+		{"USER": "alice", "PASS": "1"},
+		{"USER": "alice", "PASS": "2"},
+		{"USER": "alice", "PASS": "3"},
+		{"USER": "bob", "PASS": "1"},
+		{"USER": "bob", "PASS": "2"},
+		{"USER": "bob", "PASS": "3"},
+	}, readAll(t, combination))
+
+	_, err := combination.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestClusterBombWithEmptyList(t *testing.T) {
+	combination := ClusterBomb(keywordList("USER", "alice\nbob"), keywordList("PASS", ""))
+	assert.Equal(t, 0, len(readAll(t, combination)))
+}
+
+func TestPitchfork(t *testing.T) {
+	combination := Pitchfork(keywordList("USER", "alice\nbob\ncarol"), keywordList("PASS", "1\n2"))
+
+	assert.Equal(t, []map[string]string{
+		{"USER": "alice", "PASS": "1"},
+		{"USER": "bob", "PASS": "2"},
+	}, readAll(t, combination))
+}
+
+func TestCombinationSeekTo(t *testing.T) {
+	combination := ClusterBomb(keywordList("A", "1\n2"), keywordList("B", "x\ny"))
+
+	require.NoError(t, combination.SeekTo(3))
+	assert.Equal(t, int64(3), combination.Position())
+
+	word, err := combination.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "2,y", word)
+
+	assert.Error(t, combination.SeekTo(1))
+}
@@ -7,8 +7,9 @@ import (
 )
 
 type ReaderWordlist struct {
-	handle  io.ReadCloser
-	scanner *bufio.Scanner
+	handle   io.ReadCloser
+	scanner  *bufio.Scanner
+	position int64
 }
 
 // FromFile creates a WordList from a file on disk. The file should includes words separated by new lines.
@@ -2,6 +2,7 @@ package wordlist
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 )
 
@@ -21,5 +22,24 @@ func (fw *ReaderWordlist) Next() (string, error) {
 		}
 		return "", io.EOF
 	}
+	fw.position++
 	return fw.scanner.Text(), nil
 }
+
+// Position returns the number of words read so far
+func (fw *ReaderWordlist) Position() int64 {
+	return fw.position
+}
+
+// SeekTo skips forward until the given number of words have been read. Readers cannot be rewound, so seeking backwards is an error.
+func (fw *ReaderWordlist) SeekTo(position int64) error {
+	if position < fw.position {
+		return fmt.Errorf("cannot seek backwards from word %d to word %d", fw.position, position)
+	}
+	for fw.position < position {
+		if _, err := fw.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,3 +3,10 @@ package wordlist
 type Wordlist interface {
 	Next() (string, error)
 }
+
+// Seekable is a Wordlist which can report how far through it is, and skip to a given position, allowing a scan to be resumed
+type Seekable interface {
+	Wordlist
+	Position() int64
+	SeekTo(position int64) error
+}